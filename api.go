@@ -0,0 +1,408 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// APIDescriptor answers / in -api-only mode with a short description of
+// the service instead of the HTML index.
+func (application *App) APIDescriptor(response http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/" {
+		application.notFound(response, request)
+		return
+	}
+	writeJSON(response, http.StatusOK, map[string]any{
+		"service":  "tasks",
+		"version":  "v1",
+		"tasks":    "/api/v1/tasks",
+		"readOnly": application.config.ReadOnly,
+	})
+}
+
+// APIGetTasks returns a page of tasks as JSON in a PageResponse. Pagination
+// is controlled by the limit and offset query parameters, and Link headers
+// point at the neighbouring pages. The TaskFilter parameters narrow the
+// result.
+func (application *App) APIGetTasks(response http.ResponseWriter, request *http.Request) {
+	if value := request.URL.Query().Get("modifiedSince"); value != "" {
+		application.apiSyncTasks(response, value)
+		return
+	}
+
+	limit, offset, err := parsePagination(request.URL.Query())
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseTaskFilter(request.URL.Query(), application.config.Enabled[featureSearch])
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	where, args := filter.where()
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	var total int
+	err = application.queryRow("SELECT COUNT(*) FROM tasks"+where, args...).Scan(&total)
+	if err != nil {
+		dbError(response, "Error counting tasks", err)
+		return
+	}
+
+	rows, err := application.query("SELECT "+taskColumns+" FROM tasks"+where+" ORDER BY position, id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		dbError(response, "Error fetching tasks", err)
+		return
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		dbError(response, "Error scanning task", err)
+		return
+	}
+
+	page := newPageResponse(request, tasks, limit, offset, total)
+	if links := page.linkHeader(); links != "" {
+		response.Header().Set("Link", links)
+	}
+	writeJSON(response, http.StatusOK, page)
+}
+
+// SyncResponse lists the tasks changed and deleted since a point in time.
+// Clients pass ServerTime as modifiedSince on their next sync.
+type SyncResponse struct {
+	Items      []Task    `json:"items"`
+	Deleted    []int64   `json:"deleted"`
+	ServerTime time.Time `json:"serverTime"`
+}
+
+// apiSyncTasks answers GET /api/v1/tasks?modifiedSince=<RFC 3339>. The
+// comparison is inclusive, so a change may be reported twice but never
+// missed.
+func (application *App) apiSyncTasks(response http.ResponseWriter, value string) {
+	since, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		http.Error(response, "modifiedSince must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+	cutoff := since.UTC().Format(sqliteMillisLayout)
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	changes := SyncResponse{ServerTime: time.Now().UTC(), Deleted: []int64{}}
+
+	rows, err := application.query("SELECT "+taskColumns+" FROM tasks WHERE updated_at >= ? ORDER BY updated_at", cutoff)
+	if err != nil {
+		dbError(response, "Error fetching tasks", err)
+		return
+	}
+	changes.Items, err = scanTasks(rows)
+	rows.Close()
+	if err != nil {
+		dbError(response, "Error scanning task", err)
+		return
+	}
+
+	rows, err = application.query("SELECT id FROM task_tombstones WHERE deleted_at >= ? ORDER BY deleted_at", cutoff)
+	if err != nil {
+		dbError(response, "Error fetching deleted tasks", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			dbError(response, "Error scanning deleted task", err)
+			return
+		}
+		changes.Deleted = append(changes.Deleted, id)
+	}
+
+	writeJSON(response, http.StatusOK, changes)
+}
+
+// APICreateTask adds a task from a JSON body of the form
+// {"task": "...", "estimateMinutes": 30, "listId": 1} and returns it with a
+// 201, so clients learn the new id. estimateMinutes and listId are
+// optional.
+func (application *App) APICreateTask(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Task            string `json:"task"`
+		EstimateMinutes int    `json:"estimateMinutes"`
+		ListID          *int64 `json:"listId"`
+	}
+	request.Body = http.MaxBytesReader(response, request.Body, maxJSONBodyBytes)
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		http.Error(response, "Error parsing body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task := application.normalize(body.Task)
+	if task == "" {
+		http.Error(response, "Task cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if application.containsBlockedWord(task) {
+		http.Error(response, "Task contains a blocked word", http.StatusBadRequest)
+		return
+	}
+	if body.EstimateMinutes < 0 {
+		http.Error(response, "Invalid estimate: minutes must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	listID := defaultListID
+	if body.ListID != nil {
+		listID = *body.ListID
+	}
+
+	created, err := application.createTask(task, body.EstimateMinutes, listID)
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error adding task", err)
+		return
+	}
+	response.Header().Set("Location", fmt.Sprintf("/api/v1/tasks/%d", created.ID))
+	writeJSON(response, http.StatusCreated, created)
+}
+
+// APIGetTask returns a single task as JSON, addressed by the id in the path
+// /api/v1/tasks/{id}.
+func (application *App) APIGetTask(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(request.URL.Path, "/api/v1/tasks/"), 10, 64)
+	if err != nil {
+		application.notFound(response, request)
+		return
+	}
+
+	task, err := application.querySingleTask(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(response, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error fetching task", err)
+		return
+	}
+	writeJSON(response, http.StatusOK, task)
+}
+
+const maxJSONBodyBytes = 1 << 20
+
+// errOrderMismatch is returned when a reorder request does not list exactly
+// the current set of tasks in the list.
+var errOrderMismatch = errors.New("order must list every task in the list exactly once")
+
+// APIReorderTasks persists a new task order within a list. The body is
+// {"order": [ids], "listId": 1} and must contain every task id of the list
+// exactly once; the first id is shown first. listId defaults to the default
+// list.
+func (application *App) APIReorderTasks(response http.ResponseWriter, request *http.Request) {
+	var body struct {
+		Order  []int64 `json:"order"`
+		ListID *int64  `json:"listId"`
+	}
+	request.Body = http.MaxBytesReader(response, request.Body, maxJSONBodyBytes)
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		http.Error(response, "Error parsing body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listID := defaultListID
+	if body.ListID != nil {
+		listID = *body.ListID
+	}
+
+	application.mu.Lock()
+	err := application.reorderTasks(listID, body.Order)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errOrderMismatch) {
+		http.Error(response, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error reordering tasks", err)
+		return
+	}
+	response.WriteHeader(http.StatusNoContent)
+}
+
+// reorderTasks rewrites the position of every task in the list in one
+// transaction. The caller must hold the mutex.
+func (application *App) reorderTasks(listID int64, order []int64) error {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM lists WHERE id = ?)", listID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errListNotFound
+	}
+
+	rows, err := tx.Query("SELECT id FROM tasks WHERE list_id = ?", listID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = false
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(order) != len(existing) {
+		return errOrderMismatch
+	}
+	for _, id := range order {
+		seen, ok := existing[id]
+		if !ok || seen {
+			return errOrderMismatch
+		}
+		existing[id] = true
+	}
+
+	for position, id := range order {
+		if _, err := tx.Exec("UPDATE tasks SET position = ? WHERE id = ?", position+1, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// querySingleTask loads the task with the given id. It returns
+// sql.ErrNoRows when no such task exists.
+func (application *App) querySingleTask(id int64) (Task, error) {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	return scanTask(application.queryRow("SELECT "+taskColumns+" FROM tasks WHERE id = ?", id))
+}
+
+// parsePagination reads limit and offset from the query string, applying
+// defaults and clamping the limit to maxPageLimit.
+func parsePagination(query url.Values) (limit, offset int, err error) {
+	limit = defaultPageLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return 0, 0, errors.New("limit must be a positive integer")
+		}
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// PageResponse is the envelope returned by the paginated JSON list
+// endpoints. Next and Prev are empty on the last and first page.
+type PageResponse[T any] struct {
+	Items  []T    `json:"items"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Next   string `json:"next,omitempty"`
+	Prev   string `json:"prev,omitempty"`
+}
+
+// newPageResponse wraps a page of items, computing the neighbouring page
+// URLs from the request.
+func newPageResponse[T any](request *http.Request, items []T, limit, offset, total int) PageResponse[T] {
+	page := PageResponse[T]{Items: items, Total: total, Limit: limit, Offset: offset}
+	if offset > 0 {
+		page.Prev = pageURL(request, limit, max(offset-limit, 0))
+	}
+	if offset+limit < total {
+		page.Next = pageURL(request, limit, offset+limit)
+	}
+	return page
+}
+
+// linkHeader builds a Link header value with rel="prev" and rel="next"
+// entries for the page.
+func (page PageResponse[T]) linkHeader() string {
+	var links []string
+	if page.Prev != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, page.Prev))
+	}
+	if page.Next != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, page.Next))
+	}
+	return strings.Join(links, ", ")
+}
+
+func pageURL(request *http.Request, limit, offset int) string {
+	scheme := "http"
+	if request.TLS != nil {
+		scheme = "https"
+	}
+
+	query := request.URL.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+
+	link := url.URL{Scheme: scheme, Host: request.Host, Path: request.URL.Path, RawQuery: query.Encode()}
+	return link.String()
+}
+
+func writeJSON(response http.ResponseWriter, status int, value any) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(status)
+	encoder := json.NewEncoder(response)
+	// Pagination URLs contain & which would otherwise be escaped as \u0026
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		slog.Error("Error encoding JSON response", "error", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func reorderRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/tasks/order", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestReorderTasksIsScopedToList(t *testing.T) {
+	application := newTestApp(t)
+	result, err := application.db.Exec("INSERT INTO lists (name) VALUES ('Errands')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listID, _ := result.LastInsertId()
+	first, _ := application.createTask("first", 0, listID)
+	second, _ := application.createTask("second", 0, listID)
+	other, _ := application.createTask("other list", 0, defaultListID)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"whole list", fmt.Sprintf(`{"listId": %d, "order": [%d, %d]}`, listID, second.ID, first.ID), http.StatusNoContent},
+		{"missing task", fmt.Sprintf(`{"listId": %d, "order": [%d]}`, listID, second.ID), http.StatusConflict},
+		{"task from another list", fmt.Sprintf(`{"listId": %d, "order": [%d, %d, %d]}`, listID, second.ID, first.ID, other.ID), http.StatusConflict},
+		{"default list", fmt.Sprintf(`{"order": [%d]}`, other.ID), http.StatusNoContent},
+		{"unknown list", `{"listId": 99, "order": []}`, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := application.serve(reorderRequest(test.body))
+			if recorder.Code != test.want {
+				t.Errorf("status %d, want %d: %s", recorder.Code, test.want, recorder.Body)
+			}
+		})
+	}
+
+	rows, err := application.db.Query("SELECT id FROM tasks WHERE list_id = ? ORDER BY position", listID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var order []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		order = append(order, id)
+	}
+	if len(order) != 2 || order[0] != second.ID || order[1] != first.ID {
+		t.Errorf("list order %v, want [%d %d]", order, second.ID, first.ID)
+	}
+}
+
+// decodeJSON decodes the recorded response body into v.
+func decodeJSON(t *testing.T, recorder *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+}
+
+func TestTaskJSONIncludesListID(t *testing.T) {
+	application := newTestApp(t)
+	result, err := application.db.Exec("INSERT INTO lists (name) VALUES ('Errands')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listID, _ := result.LastInsertId()
+
+	recorder := application.serve(httptest.NewRequest(http.MethodPost, "/api/v1/tasks",
+		strings.NewReader(fmt.Sprintf(`{"task": "Buy milk", "listId": %d}`, listID))))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, want 201: %s", recorder.Code, recorder.Body)
+	}
+	var created Task
+	decodeJSON(t, recorder, &created)
+	if created.ListID != listID {
+		t.Errorf("created task has listId %d, want %d", created.ListID, listID)
+	}
+	application.createTask("Default list task", 0, defaultListID)
+
+	var single Task
+	decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/tasks/%d", created.ID), nil)), &single)
+	if single.ListID != listID {
+		t.Errorf("GET /api/v1/tasks/{id}: listId %d, want %d", single.ListID, listID)
+	}
+
+	var sync SyncResponse
+	decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks?modifiedSince=2000-01-01T00:00:00Z", nil)), &sync)
+	lists := make(map[string]int64)
+	for _, task := range sync.Items {
+		lists[task.Task] = task.ListID
+	}
+	if lists["Buy milk"] != listID || lists["Default list task"] != defaultListID {
+		t.Errorf("sync items are in lists %v, want Buy milk in %d and Default list task in %d", lists, listID, defaultListID)
+	}
+
+	path, err := application.exportToFile(t.TempDir(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []Task
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range exported {
+		if want := lists[task.Task]; task.ListID != want {
+			t.Errorf("exported %q with listId %d, want %d", task.Task, task.ListID, want)
+		}
+	}
+}
+
+func TestTaskJSONIncludesCompletedAt(t *testing.T) {
+	application := newTestApp(t)
+	done, _ := application.createTask("done", 0, defaultListID)
+	pending, _ := application.createTask("pending", 0, defaultListID)
+	before := time.Now().UTC().Truncate(time.Second)
+	recorder := application.serve(postForm("/completeTask", url.Values{
+		"taskId":    {strconv.FormatInt(done.ID, 10)},
+		"completed": {"true"},
+	}))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("complete: status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	check := func(source string, tasks []Task) {
+		t.Helper()
+		for _, task := range tasks {
+			switch task.ID {
+			case done.ID:
+				if !task.CompletedAt.Valid || task.CompletedAt.Time.Before(before) || task.CompletedAt.Time.Location() != time.UTC {
+					t.Errorf("%s: completedAt %v, want a UTC time from %v on", source, task.CompletedAt, before)
+				}
+			case pending.ID:
+				if task.CompletedAt.Valid {
+					t.Errorf("%s: pending task has completedAt %v", source, task.CompletedAt.Time)
+				}
+			}
+		}
+	}
+
+	var single Task
+	recorder = application.serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/tasks/%d", pending.ID), nil))
+	if !strings.Contains(recorder.Body.String(), `"completedAt":null`) {
+		t.Errorf("pending task JSON %s does not have a null completedAt", recorder.Body)
+	}
+	decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/tasks/%d", done.ID), nil)), &single)
+	check("GET /api/v1/tasks/{id}", []Task{single})
+
+	var page struct {
+		Items []Task `json:"items"`
+	}
+	decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)), &page)
+	if len(page.Items) != 2 {
+		t.Fatalf("GET /api/v1/tasks returned %d tasks, want 2", len(page.Items))
+	}
+	check("GET /api/v1/tasks", page.Items)
+
+	var sync SyncResponse
+	decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks?modifiedSince=2000-01-01T00:00:00Z", nil)), &sync)
+	check("sync", sync.Items)
+
+	path, err := application.exportToFile(t.TempDir(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []Task
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	check("export", exported)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backupRetryAfter is the Retry-After hint, in seconds, sent to writers
+// rejected while a backup is running.
+const backupRetryAfter = "30"
+
+// Backup writes a consistent copy of the database to the backup directory
+// using VACUUM INTO. While it runs, reads are served normally but writes
+// are rejected with 503 so the snapshot reflects a quiet database.
+func (application *App) Backup(response http.ResponseWriter, request *http.Request) {
+	application.mu.Lock()
+	if application.maintenance {
+		application.mu.Unlock()
+		http.Error(response, "A backup is already running", http.StatusConflict)
+		return
+	}
+	application.maintenance = true
+	application.mu.Unlock()
+
+	defer func() {
+		application.mu.Lock()
+		application.maintenance = false
+		application.mu.Unlock()
+	}()
+
+	if err := os.MkdirAll(application.config.BackupDir, 0o755); err != nil {
+		http.Error(response, "Error creating backup directory: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	path := filepath.Join(application.config.BackupDir, fmt.Sprintf("tasks-%s.db", time.Now().UTC().Format("20060102T150405.000Z")))
+	if _, err := application.exec("VACUUM INTO ?", path); err != nil {
+		dbError(response, "Error backing up database", err)
+		return
+	}
+
+	slog.Info("Database backed up", "path", path)
+	writeJSON(response, http.StatusOK, map[string]string{"path": path})
+}
+
+func (application *App) inMaintenance() bool {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+	return application.maintenance
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// loadBlocklist reads a newline-delimited word list. Blank lines are
+// ignored and words are matched case-insensitively.
+func loadBlocklist(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	words := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if word := strings.ToLower(strings.TrimSpace(scanner.Text())); word != "" {
+			words[word] = struct{}{}
+		}
+	}
+	return words, scanner.Err()
+}
+
+// containsBlockedWord reports whether any whole word of text is on the
+// blocklist. Words are split on anything that is not a letter or digit.
+func (application *App) containsBlockedWord(text string) bool {
+	if len(application.blocklist) == 0 {
+		return false
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		if _, blocked := application.blocklist[word]; blocked {
+			return true
+		}
+	}
+	return false
+}
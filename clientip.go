@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// trustedProxies are the networks whose X-Forwarded-For headers are
+// believed. Requests from anywhere else are identified by RemoteAddr alone.
+type trustedProxies []netip.Prefix
+
+// parseTrustedProxies parses a comma-separated list of CIDR ranges. Bare
+// addresses are treated as single-host ranges.
+func parseTrustedProxies(list string) (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q: %w", field, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q: %w", field, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (proxies trustedProxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that sent request. When the
+// direct peer is a trusted proxy, X-Forwarded-For is walked from the right
+// and the first address that is not itself a trusted proxy wins.
+func (proxies trustedProxies) clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	if !proxies.contains(peer) {
+		return peer.Unmap().String()
+	}
+
+	hops := strings.Split(strings.Join(request.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed entry cannot be trusted or skipped past
+			break
+		}
+		peer = hop
+		if !proxies.contains(hop) {
+			break
+		}
+	}
+	return peer.Unmap().String()
+}
+
+// accessLog logs every request at debug level with the client address
+// resolved through proxies.
+func accessLog(next http.Handler, proxies trustedProxies) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(response, request)
+		slog.Debug("Request handled", "method", request.Method, "path", request.URL.Path,
+			"client", proxies.clientIP(request), "duration", time.Since(start))
+	})
+}
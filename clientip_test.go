@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.1, fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"no proxy", "203.0.113.5:1234", nil, "203.0.113.5"},
+		{"untrusted peer spoofing XFF", "203.0.113.5:1234", []string{"198.51.100.7"}, "203.0.113.5"},
+		{"trusted peer", "10.0.0.1:1234", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"trusted peer without XFF", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"chain of trusted hops", "10.0.0.1:1234", []string{"198.51.100.7, 192.0.2.1, 10.1.2.3"}, "198.51.100.7"},
+		{"spoofed entry left of the client", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.7, 10.1.2.3"}, "198.51.100.7"},
+		{"every hop trusted", "10.0.0.1:1234", []string{"10.2.0.1, 10.1.0.1"}, "10.2.0.1"},
+		{"malformed entry", "10.0.0.1:1234", []string{"1.2.3.4, not-an-ip, 10.1.2.3"}, "10.1.2.3"},
+		{"malformed last entry", "10.0.0.1:1234", []string{"1.2.3.4, garbage"}, "10.0.0.1"},
+		{"several headers", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.7", "10.1.2.3"}, "198.51.100.7"},
+		{"several headers, client in the last", "10.0.0.1:1234", []string{"1.2.3.4", "198.51.100.7"}, "198.51.100.7"},
+		{"IPv4-mapped trusted peer", "[::ffff:10.0.0.1]:1234", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"IPv4-mapped untrusted peer", "[::ffff:203.0.113.5]:1234", []string{"198.51.100.7"}, "203.0.113.5"},
+		{"IPv4-mapped hop", "10.0.0.1:1234", []string{"::ffff:198.51.100.7, ::ffff:10.1.2.3"}, "198.51.100.7"},
+		{"IPv6 trusted peer", "[fd00::1]:1234", []string{"2001:db8::7"}, "2001:db8::7"},
+		{"remote address without port", "203.0.113.5", []string{"198.51.100.7"}, "203.0.113.5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = test.remoteAddr
+			for _, value := range test.forwarded {
+				request.Header.Add("X-Forwarded-For", value)
+			}
+			if got := proxies.clientIP(request); got != test.want {
+				t.Errorf("clientIP = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.7")
+	if got := trustedProxies(nil).clientIP(request); got != "10.0.0.1" {
+		t.Errorf("clientIP = %q, want the peer 10.0.0.1", got)
+	}
+}
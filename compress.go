@@ -0,0 +1,99 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compress gzips responses for clients that accept it, at the given
+// compress/gzip level. Level 0 disables compression.
+func compress(next http.Handler, level int) http.Handler {
+	if level == gzip.NoCompression {
+		return next
+	}
+	writers := sync.Pool{New: func() any {
+		// The level was validated at startup, so this cannot fail
+		writer, _ := gzip.NewWriterLevel(io.Discard, level)
+		return writer
+	}}
+
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Add("Vary", "Accept-Encoding")
+		if request.Method == http.MethodHead || !acceptsGzip(request.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(response, request)
+			return
+		}
+
+		gzipped := &gzipResponseWriter{ResponseWriter: response, pool: &writers}
+		defer gzipped.close()
+		next.ServeHTTP(gzipped, request)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header lists gzip without
+// refusing it with q=0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		value, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		quality, err := strconv.ParseFloat(value, 64)
+		return err == nil && quality > 0
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body of successful responses. Whether
+// to compress is decided once the status is known, so error, 304 and range
+// responses pass through untouched.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	writer      *gzip.Writer
+	wroteHeader bool
+}
+
+func (gzipped *gzipResponseWriter) WriteHeader(status int) {
+	if gzipped.wroteHeader {
+		return
+	}
+	gzipped.wroteHeader = true
+
+	header := gzipped.Header()
+	if status == http.StatusOK && header.Get("Content-Encoding") == "" && header.Get("Content-Range") == "" {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gzipped.writer = gzipped.pool.Get().(*gzip.Writer)
+		gzipped.writer.Reset(gzipped.ResponseWriter)
+	}
+	gzipped.ResponseWriter.WriteHeader(status)
+}
+
+func (gzipped *gzipResponseWriter) Write(body []byte) (int, error) {
+	if !gzipped.wroteHeader {
+		gzipped.WriteHeader(http.StatusOK)
+	}
+	if gzipped.writer == nil {
+		return gzipped.ResponseWriter.Write(body)
+	}
+	return gzipped.writer.Write(body)
+}
+
+// close flushes the compressed stream and returns the writer to the pool.
+func (gzipped *gzipResponseWriter) close() {
+	if gzipped.writer == nil {
+		return
+	}
+	gzipped.writer.Close()
+	gzipped.pool.Put(gzipped.writer)
+	gzipped.writer = nil
+}
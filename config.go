@@ -0,0 +1,302 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// envPrefix is prepended to the upper-cased flag name to form the
+// environment variable consulted when a flag is not given, e.g.
+// TASKS_READ_ONLY for -read-only.
+const envPrefix = "TASKS_"
+
+// Config holds every setting of the app. It is populated once at startup
+// by loadConfig and not modified afterwards.
+type Config struct {
+	Addr            string
+	DBPath          string
+	TLSCert         string
+	TLSKey          string
+	H2C             bool
+	ShutdownTimeout time.Duration
+	RequestTimeout  time.Duration
+	MaxConcurrent   int
+	GzipLevel       int
+
+	ReadHeaderTimeout time.Duration
+	MaxHeaderBytes    int
+
+	ReadOnly          bool
+	APIOnly           bool
+	Features          string
+	CascadeListDelete bool
+	EmptyEditDeletes  bool
+	NormalizeUnicode  bool
+	BlocklistFile     string
+	ObfuscateIDs      bool
+	Dev               bool
+	CompletedInline   bool
+	PollInterval      time.Duration
+	StaticMaxAge      time.Duration
+
+	AutoExportDir      string
+	AutoExportInterval time.Duration
+	AutoExportKeep     int
+	CompletedRetention time.Duration
+	MaxExportLen       int
+
+	CORSOrigin     string
+	CORSMaxAge     int
+	TrustedProxies string
+
+	AdminToken         string
+	BackupDir          string
+	SlowQueryThreshold time.Duration
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	DigestTo     string
+	DigestTime   string
+	QuietStart   string
+	QuietEnd     string
+
+	Timezone string
+	LogLevel string
+
+	// Chaos injects latency and failures for testing clients. The flags are
+	// left out of -help so they are not mistaken for production settings.
+	Chaos          bool
+	ChaosDelay     time.Duration
+	ChaosErrorRate float64
+
+	// Resolved from Timezone, LogLevel, TrustedProxies, DigestTime, the
+	// quiet hours and Features by validate. The offsets are times of day as
+	// offsets from midnight.
+	Location         *time.Location
+	Level            slog.Level
+	Proxies          trustedProxies
+	DigestOffset     time.Duration
+	QuietStartOffset time.Duration
+	QuietEndOffset   time.Duration
+	Enabled          featureSet
+}
+
+// loadConfig parses args into a Config. Flags that are not given fall back
+// to their environment variable, then to the built-in default.
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	flags := flag.NewFlagSet("tasks", flag.ContinueOnError)
+
+	flags.StringVar(&cfg.Addr, "addr", ":8080", "address to listen on")
+	flags.StringVar(&cfg.DBPath, "db", "./tasks.db", "path to the SQLite database")
+	flags.StringVar(&cfg.TLSCert, "tls-cert", "", "TLS certificate file; serves HTTPS with HTTP/2 when set with -tls-key")
+	flags.StringVar(&cfg.TLSKey, "tls-key", "", "TLS private key file")
+	flags.BoolVar(&cfg.H2C, "h2c", false, "accept cleartext HTTP/2 (h2c), for use behind a TLS-terminating proxy")
+	flags.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", 10*time.Second, "time allowed for in-flight requests on shutdown")
+	flags.IntVar(&cfg.MaxConcurrent, "max-concurrent-requests", 0, "requests handled at once before new ones get a 503 (unlimited when zero)")
+	flags.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", 10*time.Second, "time allowed for a client to send the request headers")
+	flags.IntVar(&cfg.MaxHeaderBytes, "max-header-bytes", 64<<10, "maximum size of the request headers in bytes")
+	flags.IntVar(&cfg.GzipLevel, "gzip-level", gzip.DefaultCompression, "gzip level from 1 (fastest) to 9 (smallest) for compressed responses, 0 to disable")
+	flags.DurationVar(&cfg.RequestTimeout, "request-timeout", 30*time.Second, "maximum time a handler may take before the client gets a 503 (disabled when zero)")
+
+	flags.BoolVar(&cfg.ReadOnly, "read-only", false, "reject requests that modify tasks")
+	flags.BoolVar(&cfg.APIOnly, "api-only", false, "serve only the JSON API, without the HTML pages")
+	flags.StringVar(&cfg.Features, "features", strings.Join(allFeatures, ","), "comma-separated optional features to enable: "+strings.Join(allFeatures, ", "))
+	flags.BoolVar(&cfg.CascadeListDelete, "cascade-list-delete", false, "delete a list's tasks along with it instead of refusing to delete non-empty lists")
+	flags.BoolVar(&cfg.EmptyEditDeletes, "empty-edit-deletes", false, "delete a task when it is edited to empty text instead of rejecting the edit")
+	flags.BoolVar(&cfg.NormalizeUnicode, "normalize-unicode", false, "normalize task text to Unicode NFC before storing")
+	flags.StringVar(&cfg.BlocklistFile, "blocklist-file", "", "newline-delimited list of words rejected in task text")
+	flags.BoolVar(&cfg.ObfuscateIDs, "obfuscate-ids", false, "show opaque identifiers instead of raw task ids in the UI")
+	flags.BoolVar(&cfg.Dev, "dev", false, "include debugging details in rendered pages")
+	flags.BoolVar(&cfg.CompletedInline, "show-completed-inline", false, "keep completed tasks in the main list, struck through, instead of only in the completed view")
+	flags.DurationVar(&cfg.StaticMaxAge, "static-max-age", time.Hour, "how long browsers may cache files under /static/ before revalidating")
+	flags.DurationVar(&cfg.PollInterval, "poll-interval", 0, "how often the page reloads the task list in the background (disabled when zero)")
+
+	flags.StringVar(&cfg.AutoExportDir, "auto-export-dir", "", "directory for periodic JSON exports of all tasks (disabled when empty)")
+	flags.DurationVar(&cfg.AutoExportInterval, "auto-export-interval", time.Hour, "interval between automatic exports")
+	flags.IntVar(&cfg.AutoExportKeep, "auto-export-keep", 10, "number of automatic exports to keep")
+	flags.DurationVar(&cfg.CompletedRetention, "completed-retention", 0, "delete tasks completed longer ago than this (disabled when zero)")
+	flags.IntVar(&cfg.MaxExportLen, "max-export-len", 0, "truncate task text in Markdown exports to this many characters (disabled when zero)")
+
+	flags.StringVar(&cfg.CORSOrigin, "cors-origin", "", "origin allowed to call the JSON API cross-origin (disabled when empty)")
+	flags.IntVar(&cfg.CORSMaxAge, "cors-max-age", 600, "seconds browsers may cache CORS preflight responses")
+	flags.StringVar(&cfg.TrustedProxies, "trusted-proxies", "", "comma-separated CIDR ranges of proxies whose X-Forwarded-For is trusted")
+
+	flags.StringVar(&cfg.AdminToken, "admin-token", "", "bearer token for the /admin/ endpoints (disabled when empty)")
+	flags.StringVar(&cfg.BackupDir, "backup-dir", "./backups", "directory for backups taken via /admin/backup")
+	flags.DurationVar(&cfg.SlowQueryThreshold, "slow-query-threshold", 0, "log queries slower than this (disabled when zero)")
+
+	flags.StringVar(&cfg.SMTPHost, "smtp-host", "", "SMTP server used to send the digest (digest disabled when empty)")
+	flags.IntVar(&cfg.SMTPPort, "smtp-port", 587, "SMTP server port")
+	flags.StringVar(&cfg.SMTPUsername, "smtp-username", "", "SMTP username (no authentication when empty)")
+	flags.StringVar(&cfg.SMTPPassword, "smtp-password", "", "SMTP password")
+	flags.StringVar(&cfg.SMTPFrom, "smtp-from", "", "sender address for the digest")
+	flags.StringVar(&cfg.DigestTo, "digest-to", "", "address that receives the daily digest of pending tasks (disabled when empty)")
+	flags.StringVar(&cfg.DigestTime, "digest-time", "08:00", "time of day to send the digest, in the -tz timezone")
+	flags.StringVar(&cfg.QuietStart, "quiet-hours-start", "", "time of day from which notifications are held back, in the -tz timezone (disabled when empty)")
+	flags.StringVar(&cfg.QuietEnd, "quiet-hours-end", "", "time of day at which held back notifications are sent")
+
+	flags.StringVar(&cfg.Timezone, "tz", "Local", "IANA timezone used for day boundaries, e.g. Europe/Bucharest")
+	flags.StringVar(&cfg.LogLevel, "log-level", "info", "minimum log level: debug, info, warn or error")
+
+	flags.BoolVar(&cfg.Chaos, "chaos", false, "inject random latency and failures; never use in production")
+	flags.DurationVar(&cfg.ChaosDelay, "chaos-delay", 500*time.Millisecond, "maximum latency added to each request in chaos mode")
+	flags.Float64Var(&cfg.ChaosErrorRate, "chaos-error-rate", 0.1, "fraction of requests failed with a 500 in chaos mode")
+	hideFlags(flags, "chaos", "chaos-delay", "chaos-error-rate")
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var envErr error
+	flags.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || envErr != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+		if value, ok := lookupEnv(name); ok {
+			if err := flags.Set(f.Name, value); err != nil {
+				envErr = fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	})
+	if envErr != nil {
+		return Config{}, envErr
+	}
+
+	return cfg, cfg.validate()
+}
+
+// validate checks settings that depend on each other and resolves the
+// timezone, log level, trusted proxies, digest time, quiet hours and
+// features.
+func (cfg *Config) validate() error {
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return errors.New("-tls-cert and -tls-key must be set together")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		return errors.New("-shutdown-timeout must be positive")
+	}
+	if cfg.AutoExportDir != "" && (cfg.AutoExportInterval <= 0 || cfg.AutoExportKeep < 1) {
+		return errors.New("-auto-export-interval must be positive and -auto-export-keep at least 1")
+	}
+	if cfg.CompletedRetention < 0 || cfg.SlowQueryThreshold < 0 {
+		return errors.New("-completed-retention and -slow-query-threshold cannot be negative")
+	}
+	if cfg.MaxExportLen < 0 || cfg.PollInterval < 0 || cfg.RequestTimeout < 0 || cfg.StaticMaxAge < 0 {
+		return errors.New("-max-export-len, -poll-interval, -request-timeout and -static-max-age cannot be negative")
+	}
+	if cfg.GzipLevel < gzip.DefaultCompression || cfg.GzipLevel > gzip.BestCompression {
+		return errors.New("-gzip-level must be between 1 and 9, -1 for the default or 0 to disable compression")
+	}
+	if cfg.ReadHeaderTimeout <= 0 || cfg.MaxHeaderBytes <= 0 {
+		// Zero would silently fall back to no timeout or net/http's 1 MB
+		return errors.New("-read-header-timeout and -max-header-bytes must be positive")
+	}
+	if cfg.MaxConcurrent < 0 {
+		return errors.New("-max-concurrent-requests cannot be negative")
+	}
+	if cfg.ChaosDelay < 0 || cfg.ChaosErrorRate < 0 || cfg.ChaosErrorRate > 1 {
+		return errors.New("-chaos-delay cannot be negative and -chaos-error-rate must be between 0 and 1")
+	}
+	if cfg.digestEnabled() && cfg.SMTPFrom == "" {
+		return errors.New("-smtp-from is required to send the digest")
+	}
+
+	var err error
+	cfg.DigestOffset, err = parseClock(cfg.DigestTime)
+	if err != nil {
+		return fmt.Errorf("invalid -digest-time, want HH:MM: %w", err)
+	}
+	if (cfg.QuietStart == "") != (cfg.QuietEnd == "") {
+		return errors.New("-quiet-hours-start and -quiet-hours-end must be set together")
+	}
+	if cfg.quietHoursEnabled() {
+		if cfg.QuietStartOffset, err = parseClock(cfg.QuietStart); err != nil {
+			return fmt.Errorf("invalid -quiet-hours-start, want HH:MM: %w", err)
+		}
+		if cfg.QuietEndOffset, err = parseClock(cfg.QuietEnd); err != nil {
+			return fmt.Errorf("invalid -quiet-hours-end, want HH:MM: %w", err)
+		}
+		if cfg.QuietStartOffset == cfg.QuietEndOffset {
+			return errors.New("-quiet-hours-start and -quiet-hours-end must differ")
+		}
+	}
+
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid -tz: %w", err)
+	}
+	cfg.Location = location
+
+	if err := cfg.Level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("invalid -log-level: %w", err)
+	}
+
+	cfg.Proxies, err = parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid -trusted-proxies: %w", err)
+	}
+
+	cfg.Enabled, err = parseFeatures(cfg.Features)
+	if err != nil {
+		return fmt.Errorf("invalid -features: %w", err)
+	}
+	if cfg.APIOnly && !cfg.Enabled[featureAPI] {
+		return errors.New("-api-only needs the api feature enabled")
+	}
+	return nil
+}
+
+// parseClock reads an HH:MM time of day as an offset from midnight.
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// quietHoursEnabled reports whether notifications are held back during a
+// daily quiet window.
+func (cfg *Config) quietHoursEnabled() bool {
+	return cfg.QuietStart != ""
+}
+
+// digestEnabled reports whether both an SMTP server and a recipient are
+// configured for the daily digest.
+func (cfg *Config) digestEnabled() bool {
+	return cfg.SMTPHost != "" && cfg.DigestTo != ""
+}
+
+// hideFlags leaves the named flags out of the usage message. They can still
+// be set on the command line and through the environment.
+func hideFlags(flags *flag.FlagSet, names ...string) {
+	hidden := make(map[string]bool)
+	for _, name := range names {
+		hidden[name] = true
+	}
+
+	flags.Usage = func() {
+		visible := flag.NewFlagSet(flags.Name(), flag.ContinueOnError)
+		visible.SetOutput(flags.Output())
+		flags.VisitAll(func(f *flag.Flag) {
+			if !hidden[f.Name] {
+				visible.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+		fmt.Fprintf(flags.Output(), "Usage of %s:\n", flags.Name())
+		visible.PrintDefaults()
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// countsRefreshInterval is how often the cached counts are recomputed from
+// the database, in case a write path adjusted them wrongly.
+const countsRefreshInterval = 5 * time.Minute
+
+// taskCounts caches the number of pending and completed tasks so pages do
+// not need a COUNT(*) query per request. It is guarded by App.mu.
+type taskCounts struct {
+	Pending   int
+	Completed int
+
+	// stale is set when a write may have changed the counts in a way that
+	// was not tracked; the next read recomputes them.
+	stale bool
+}
+
+// refreshCounts recomputes the cached counts from the database. The caller
+// must hold the mutex.
+func (application *App) refreshCounts() error {
+	var counts taskCounts
+	err := application.queryRow(`SELECT
+		COALESCE(SUM(completed = 0), 0), COALESCE(SUM(completed = 1), 0)
+		FROM tasks`).Scan(&counts.Pending, &counts.Completed)
+	if err != nil {
+		application.counts.stale = true
+		return err
+	}
+	application.counts = counts
+	return nil
+}
+
+// currentCounts returns the cached counts, recomputing them first if they are
+// stale. The caller must hold the mutex.
+func (application *App) currentCounts() (taskCounts, error) {
+	if application.counts.stale {
+		if err := application.refreshCounts(); err != nil {
+			return taskCounts{}, err
+		}
+	}
+	return application.counts, nil
+}
+
+// adjustCounts applies the effect of a successful write to the cached
+// counts. Any change to the completed tasks also invalidates the streak. The caller must hold the mutex.
+func (application *App) adjustCounts(pending, completed int) {
+	application.counts.Pending += pending
+	application.counts.Completed += completed
+	if completed != 0 {
+		application.streak.valid = false
+	}
+}
+
+// refreshCountsPeriodically recomputes the cached counts every
+// countsRefreshInterval until ctx is cancelled.
+func (application *App) refreshCountsPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(countsRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		application.mu.Lock()
+		err := application.refreshCounts()
+		application.mu.Unlock()
+		if err != nil {
+			slog.Error("Error refreshing task counts", "error", err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+var errDependencyCycle = errors.New("dependency would create a cycle")
+
+// AddDependency records that taskId cannot be completed until dependsOnId
+// is. Adding a dependency that already exists is not an error; one that
+// would let a task transitively depend on itself is a 409.
+func (application *App) AddDependency(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := application.ids.decode(request.FormValue("taskId"))
+	if err != nil {
+		http.Error(response, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	dependsOnID, err := application.ids.decode(request.FormValue("dependsOnId"))
+	if err != nil {
+		http.Error(response, "Invalid dependency task id", http.StatusBadRequest)
+		return
+	}
+	if taskID == dependsOnID {
+		http.Error(response, "A task cannot depend on itself", http.StatusBadRequest)
+		return
+	}
+	showCompleted := request.FormValue("showCompleted") == "true"
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	err = application.addDependency(taskID, dependsOnID)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if errors.Is(err, errTaskNotFound) {
+		http.Error(response, "Task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errDependencyCycle) {
+		http.Error(response, "Dependency would create a cycle", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error adding dependency", err)
+		return
+	}
+
+	application.renderTasks(response, listID, showCompleted)
+}
+
+// addDependency checks both tasks exist and that dependsOnID does not
+// already depend on taskID, directly or transitively, then inserts the
+// dependency in the same transaction. The caller must hold the mutex.
+func (application *App) addDependency(taskID, dependsOnID int64) error {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE id IN (?, ?)", taskID, dependsOnID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return errTaskNotFound
+	}
+
+	var cycle int
+	err = tx.QueryRow(`WITH RECURSIVE reachable (id) AS (
+			SELECT ?
+			UNION
+			SELECT task_dependencies.depends_on_id FROM task_dependencies
+			JOIN reachable ON task_dependencies.task_id = reachable.id
+		)
+		SELECT 1 FROM reachable WHERE id = ?`, dependsOnID, taskID).Scan(&cycle)
+	if err == nil {
+		return errDependencyCycle
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR IGNORE INTO task_dependencies (task_id, depends_on_id) VALUES (?, ?)", taskID, dependsOnID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBlocked reports whether the task depends on any incomplete task. The
+// caller must hold the mutex.
+func (application *App) isBlocked(taskID int64) (bool, error) {
+	var blocked bool
+	err := application.queryRow(`SELECT EXISTS (
+		SELECT 1 FROM task_dependencies
+		JOIN tasks ON tasks.id = task_dependencies.depends_on_id
+		WHERE task_dependencies.task_id = ? AND tasks.completed = 0
+	)`, taskID).Scan(&blocked)
+	return blocked, err
+}
+
+// markBlocked sets Blocked on each of tasks that depends on an incomplete
+// task. The caller must hold the mutex.
+func (application *App) markBlocked(tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	rows, err := application.query(`SELECT DISTINCT task_dependencies.task_id FROM task_dependencies
+		JOIN tasks ON tasks.id = task_dependencies.depends_on_id
+		WHERE tasks.completed = 0`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	blocked := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		blocked[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		tasks[i].Blocked = blocked[tasks[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// digestMailer sends the daily email listing pending tasks.
+type digestMailer struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	to       string
+	template *template.Template
+}
+
+func newDigestMailer(cfg Config) (*digestMailer, error) {
+	tmpl, err := template.ParseFS(assets, "frontend/digest.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	mailer := &digestMailer{
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from:     cfg.SMTPFrom,
+		to:       cfg.DigestTo,
+		template: tmpl,
+	}
+	if cfg.SMTPUsername != "" {
+		mailer.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return mailer, nil
+}
+
+// nextDigest returns the first time after now at offset past midnight in
+// location.
+func nextDigest(now time.Time, offset time.Duration, location *time.Location) time.Time {
+	start, _ := dayBounds(now, location)
+	next := start.Add(offset)
+	if !next.After(now) {
+		tomorrow, _ := dayBounds(start.AddDate(0, 0, 1), location)
+		next = tomorrow.Add(offset)
+	}
+	return next
+}
+
+// quietUntil reports whether t falls within the configured quiet hours and,
+// if so, when they end. The window may span midnight, e.g. 22:00 to 07:00.
+func quietUntil(t time.Time, cfg Config) (time.Time, bool) {
+	if !cfg.quietHoursEnabled() {
+		return t, false
+	}
+	local := t.In(cfg.Location)
+	// Compare wall clock times so the window keeps its hours on DST days
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	quietStart, quietEnd := cfg.QuietStartOffset, cfg.QuietEndOffset
+	endOn := func(days int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+days, 0, int(quietEnd/time.Minute), 0, 0, cfg.Location)
+	}
+
+	if quietStart < quietEnd {
+		if clock >= quietStart && clock < quietEnd {
+			return endOn(0), true
+		}
+		return t, false
+	}
+	if clock >= quietStart {
+		return endOn(1), true
+	}
+	if clock < quietEnd {
+		return endOn(0), true
+	}
+	return t, false
+}
+
+// sendDigests emails the pending tasks once a day at offset past midnight
+// in the configured timezone until ctx is cancelled.
+func (application *App) sendDigests(ctx context.Context, mailer *digestMailer, offset time.Duration) {
+	for {
+		next := nextDigest(time.Now(), offset, application.config.Location)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if until, quiet := quietUntil(next, application.config); quiet {
+			slog.Info("Holding digest until quiet hours end", "until", until)
+			timer := time.NewTimer(time.Until(until))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			next = until
+		}
+
+		sent, err := application.sendDigest(mailer, next)
+		if err != nil {
+			slog.Error("Error sending digest", "to", mailer.to, "error", err)
+		} else if sent > 0 {
+			slog.Info("Sent digest", "to", mailer.to, "tasks", sent)
+		} else {
+			slog.Info("Skipped digest, no pending tasks")
+		}
+	}
+}
+
+// sendDigest mails the current pending tasks and returns how many were
+// listed. Nothing is sent when there are none.
+func (application *App) sendDigest(mailer *digestMailer, now time.Time) (int, error) {
+	completed := false
+	tasks, err := application.findTasks(TaskFilter{Completed: &completed})
+	if err != nil || len(tasks) == 0 {
+		return 0, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", mailer.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", mailer.to)
+	fmt.Fprintf(&msg, "Subject: %d pending tasks\r\n", len(tasks))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	if err := mailer.template.ExecuteTemplate(&msg, "digest", viewData{Tasks: tasks}); err != nil {
+		return 0, err
+	}
+
+	return len(tasks), smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{mailer.to}, msg.Bytes())
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestQuietUntil(t *testing.T) {
+	bucharest, err := time.LoadLocation("Europe/Bucharest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		at         string
+		quiet      bool
+		until      string
+	}{
+		// Europe/Bucharest is UTC+2 in January
+		{"overnight, before", "22:00", "07:00", "2026-01-15T19:59:00Z", false, ""},
+		{"overnight, at start", "22:00", "07:00", "2026-01-15T20:00:00Z", true, "2026-01-16T05:00:00Z"},
+		{"overnight, before midnight", "22:00", "07:00", "2026-01-15T21:30:00Z", true, "2026-01-16T05:00:00Z"},
+		{"overnight, after midnight", "22:00", "07:00", "2026-01-16T00:00:00Z", true, "2026-01-16T05:00:00Z"},
+		{"overnight, just before end", "22:00", "07:00", "2026-01-16T04:59:59Z", true, "2026-01-16T05:00:00Z"},
+		{"overnight, at end", "22:00", "07:00", "2026-01-16T05:00:00Z", false, ""},
+		{"overnight, midday", "22:00", "07:00", "2026-01-16T10:00:00Z", false, ""},
+
+		{"same day, before", "12:00", "14:00", "2026-01-15T09:59:00Z", false, ""},
+		{"same day, at start", "12:00", "14:00", "2026-01-15T10:00:00Z", true, "2026-01-15T12:00:00Z"},
+		{"same day, inside", "12:00", "14:00", "2026-01-15T11:30:00Z", true, "2026-01-15T12:00:00Z"},
+		{"same day, at end", "12:00", "14:00", "2026-01-15T12:00:00Z", false, ""},
+		{"same day, late evening", "12:00", "14:00", "2026-01-15T21:00:00Z", false, ""},
+
+		// Clocks go forward from 03:00 to 04:00 on 29 March 2026, so the
+		// night is an hour shorter and 07:00 is 04:00 UTC
+		{"spring DST, before midnight", "22:00", "07:00", "2026-03-28T21:00:00Z", true, "2026-03-29T04:00:00Z"},
+		{"spring DST, after the change", "22:00", "07:00", "2026-03-29T01:30:00Z", true, "2026-03-29T04:00:00Z"},
+		{"spring DST, at end", "22:00", "07:00", "2026-03-29T04:00:00Z", false, ""},
+		{"spring DST, evening start", "22:00", "07:00", "2026-03-29T19:00:00Z", true, "2026-03-30T04:00:00Z"},
+		// Clocks go back from 04:00 to 03:00 on 25 October 2026, so the
+		// night is an hour longer and 07:00 is 05:00 UTC
+		{"autumn DST, before midnight", "22:00", "07:00", "2026-10-24T20:00:00Z", true, "2026-10-25T05:00:00Z"},
+		{"autumn DST, repeated hour", "22:00", "07:00", "2026-10-25T01:30:00Z", true, "2026-10-25T05:00:00Z"},
+		{"autumn DST, at end", "22:00", "07:00", "2026-10-25T05:00:00Z", false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Config{Location: bucharest, QuietStart: test.start, QuietEnd: test.end}
+			var err error
+			if cfg.QuietStartOffset, err = parseClock(test.start); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.QuietEndOffset, err = parseClock(test.end); err != nil {
+				t.Fatal(err)
+			}
+
+			at := utc(test.at)
+			until, quiet := quietUntil(at, cfg)
+			if quiet != test.quiet {
+				t.Fatalf("quiet = %v, want %v", quiet, test.quiet)
+			}
+			want := at
+			if test.quiet {
+				want = utc(test.until)
+			}
+			if !until.Equal(want) {
+				t.Errorf("until %v, want %v", until.UTC(), want)
+			}
+		})
+	}
+}
+
+func TestQuietUntilDisabled(t *testing.T) {
+	at := time.Date(2026, 1, 15, 23, 0, 0, 0, time.UTC)
+	if until, quiet := quietUntil(at, Config{Location: time.UTC}); quiet || !until.Equal(at) {
+		t.Errorf("quietUntil without quiet hours = %v, %v; want %v, false", until, quiet, at)
+	}
+}
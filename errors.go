@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// errorPageData is the data passed to the errorPage template.
+type errorPageData struct {
+	viewData
+	Status     int
+	StatusText string
+	Message    string
+}
+
+// errorPage reports an error to the client. Browsers navigating to a page
+// get the styled errorPage template; htmx and API clients get plain text.
+func (application *App) errorPage(response http.ResponseWriter, request *http.Request, status int, message string) {
+	if !wantsHTML(request) || application.templates == nil {
+		http.Error(response, message, status)
+		return
+	}
+
+	data := errorPageData{
+		viewData:   application.newViewData(),
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+	}
+	if err := application.writeTemplate(response, status, "errorPage", data); err != nil {
+		slog.Error("Error rendering error page", "error", err)
+		http.Error(response, message, status)
+	}
+}
+
+func (application *App) notFound(response http.ResponseWriter, request *http.Request) {
+	application.errorPage(response, request, http.StatusNotFound, "Page not found")
+}
+
+func (application *App) methodNotAllowed(response http.ResponseWriter, request *http.Request) {
+	application.errorPage(response, request, http.StatusMethodNotAllowed, "Invalid request method")
+}
+
+// wantsHTML reports whether the request is a browser page load rather than
+// an htmx fragment request or an API call.
+func wantsHTML(request *http.Request) bool {
+	return request.Header.Get("HX-Request") == "" && strings.Contains(request.Header.Get("Accept"), "text/html")
+}
+
+// dbError reports a failed database operation to the client. Constraint
+// violations are caused by the request and become a 409 with a readable
+// message; anything else is a 500.
+func dbError(response http.ResponseWriter, message string, err error) {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		http.Error(response, message+": "+constraintMessage(sqliteErr), http.StatusConflict)
+		return
+	}
+	http.Error(response, message+": "+err.Error(), http.StatusInternalServerError)
+}
+
+func constraintMessage(err sqlite3.Error) string {
+	switch err.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return "an item with the same value already exists"
+	case sqlite3.ErrConstraintNotNull:
+		return "a required value is missing"
+	case sqlite3.ErrConstraintForeignKey:
+		return "it refers to an item that does not exist"
+	default:
+		return "it conflicts with existing data"
+	}
+}
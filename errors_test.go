@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func postForm(target string, form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestCreateListDuplicateNameConflicts(t *testing.T) {
+	application := newTestApp(t)
+	form := url.Values{"name": {"Groceries"}}
+
+	recorder := application.serve(postForm("/createList", form))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("first createList: status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = application.serve(postForm("/createList", form))
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("duplicate createList: status %d, want 409: %s", recorder.Code, recorder.Body)
+	}
+	if !strings.Contains(recorder.Body.String(), "already exists") {
+		t.Errorf("body %q does not explain the conflict", recorder.Body)
+	}
+}
+
+func TestDBErrorOtherErrorsAre500(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	dbError(recorder, "Error creating list", errors.New("disk I/O error"))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", recorder.Code)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const autoExportPattern = "tasks-*.json"
+
+// findTasks returns the tasks matching filter in display order.
+func (application *App) findTasks(filter TaskFilter) ([]Task, error) {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	where, args := filter.where()
+	rows, err := application.query("SELECT "+taskColumns+" FROM tasks"+where+" ORDER BY position, id DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+// autoExport writes a timestamped JSON dump of all tasks to dir every
+// interval until ctx is cancelled, keeping only the keep most recent dumps.
+func (application *App) autoExport(ctx context.Context, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			path, err := application.exportToFile(dir, now)
+			if err != nil {
+				slog.Error("Error exporting tasks", "error", err)
+				continue
+			}
+			slog.Info("Exported tasks", "path", path)
+
+			if err := pruneExports(dir, keep); err != nil {
+				slog.Error("Error pruning old exports", "error", err)
+			}
+		}
+	}
+}
+
+func (application *App) exportToFile(dir string, now time.Time) (string, error) {
+	tasks, err := application.findTasks(TaskFilter{})
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated
+	// dump behind under the final name.
+	path := filepath.Join(dir, fmt.Sprintf("tasks-%s.json", now.UTC().Format("20060102T150405.000Z")))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+// pruneExports removes all but the keep most recent dumps in dir. The
+// timestamped file names sort chronologically.
+func pruneExports(dir string, keep int) error {
+	paths, err := filepath.Glob(filepath.Join(dir, autoExportPattern))
+	if err != nil {
+		return err
+	}
+	if len(paths) <= keep {
+		return nil
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths[:len(paths)-keep] {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportMarkdown renders tasks as a Markdown checklist, pending tasks first,
+// for pasting into documents. It accepts the same filter parameters as the
+// task listings.
+func (application *App) ExportMarkdown(response http.ResponseWriter, request *http.Request) {
+	filter, err := parseTaskFilter(request.URL.Query(), application.config.Enabled[featureSearch])
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := application.findTasks(filter)
+	if err != nil {
+		dbError(response, "Error fetching tasks", err)
+		return
+	}
+
+	var pending, completed []Task
+	for _, task := range tasks {
+		if task.Completed {
+			completed = append(completed, task)
+		} else {
+			pending = append(pending, task)
+		}
+	}
+
+	var buf bytes.Buffer
+	writeMarkdownSection(&buf, "Pending", pending, application.config.MaxExportLen)
+	buf.WriteString("\n")
+	writeMarkdownSection(&buf, "Completed", completed, application.config.MaxExportLen)
+
+	response.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	response.Write(buf.Bytes())
+}
+
+// PrintTasks renders a page for printing, without any of the interactive
+// controls. It accepts the same filter parameters as the task listings and
+// shows only pending tasks unless completed is given.
+func (application *App) PrintTasks(response http.ResponseWriter, request *http.Request) {
+	filter, err := parseTaskFilter(request.URL.Query(), application.config.Enabled[featureSearch])
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Completed == nil {
+		pending := false
+		filter.Completed = &pending
+	}
+
+	tasks, err := application.findTasks(filter)
+	if err != nil {
+		dbError(response, "Error fetching tasks", err)
+		return
+	}
+
+	data := application.newViewData()
+	data.Tasks = tasks
+	data.GeneratedAt = time.Now()
+	application.render(response, "print", data)
+}
+
+func writeMarkdownSection(buf *bytes.Buffer, title string, tasks []Task, maxLen int) {
+	fmt.Fprintf(buf, "## %s\n\n", title)
+	for _, task := range tasks {
+		mark := " "
+		if task.Completed {
+			mark = "x"
+		}
+		// A newline inside the text would end the list item early
+		text := truncateText(strings.Join(strings.Fields(task.Task), " "), maxLen)
+		fmt.Fprintf(buf, "- [%s] %s\n", mark, text)
+	}
+}
+
+// truncateText shortens text to at most maxLen runes, ending it with an
+// ellipsis when anything was cut. A maxLen of zero leaves text unchanged.
+func truncateText(text string, maxLen int) string {
+	if maxLen <= 0 || utf8.RuneCountInString(text) <= maxLen {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimRightFunc(string(runes[:maxLen-1]), unicode.IsSpace) + "…"
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TaskFilter narrows the tasks returned by listings and exports. The zero
+// value matches every task.
+type TaskFilter struct {
+	// Completed restricts the result to completed or pending tasks when set.
+	Completed *bool
+	// Query matches tasks whose text contains it, ignoring case.
+	Query string
+	// ListID restricts the result to one list when set.
+	ListID *int64
+	// IDs restricts the result to the tasks with these ids when not empty.
+	// Ids that do not exist are simply not matched.
+	IDs []int64
+}
+
+// maxFilterIDs caps the ids parameter so a request cannot build an
+// arbitrarily long IN list.
+const maxFilterIDs = 100
+
+// parseTaskFilter reads the completed, q, listId and ids query parameters.
+// q is rejected unless search is set, so a server with the search feature
+// disabled does not quietly return an unfiltered result.
+func parseTaskFilter(query url.Values, search bool) (TaskFilter, error) {
+	var filter TaskFilter
+	if value := query.Get("completed"); value != "" {
+		completed, err := strconv.ParseBool(value)
+		if err != nil {
+			return TaskFilter{}, errors.New("completed must be true or false")
+		}
+		filter.Completed = &completed
+	}
+	filter.Query = strings.TrimSpace(query.Get("q"))
+	if filter.Query != "" && !search {
+		return TaskFilter{}, errors.New("q is not available because search is disabled")
+	}
+	if value := query.Get("listId"); value != "" {
+		listID, err := parseListID(value)
+		if err != nil {
+			return TaskFilter{}, err
+		}
+		filter.ListID = &listID
+	}
+	if value := query.Get("ids"); value != "" {
+		ids := strings.Split(value, ",")
+		if len(ids) > maxFilterIDs {
+			return TaskFilter{}, fmt.Errorf("ids may list at most %d tasks", maxFilterIDs)
+		}
+		for _, id := range ids {
+			parsed, err := strconv.ParseInt(strings.TrimSpace(id), 10, 64)
+			if err != nil {
+				return TaskFilter{}, errors.New("ids must be a comma-separated list of task ids")
+			}
+			filter.IDs = append(filter.IDs, parsed)
+		}
+	}
+	return filter, nil
+}
+
+// where returns the WHERE clause for the filter, or an empty string when it
+// matches everything, along with its arguments.
+func (filter TaskFilter) where() (string, []any) {
+	var conditions []string
+	var args []any
+	if filter.Completed != nil {
+		conditions = append(conditions, "tasks.completed = ?")
+		args = append(args, *filter.Completed)
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, `casefold(tasks.task) LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(filter.Query))
+	}
+	if filter.ListID != nil {
+		conditions = append(conditions, "tasks.list_id = ?")
+		args = append(args, *filter.ListID)
+	}
+	if len(filter.IDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.IDs)), ", ")
+		conditions = append(conditions, "tasks.id IN ("+placeholders+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,26 @@
+package main
+
+import "log/slog"
+
+// Hooks are callbacks run after a task lifecycle write has succeeded. They
+// are called without the mutex held, in registration order.
+type Hooks struct {
+	OnCreate   []func(Task)
+	OnComplete []func(Task)
+	OnDelete   []func(Task)
+}
+
+// runHooks calls each hook with task. A panicking hook is logged and does
+// not stop the others or the request that triggered it.
+func runHooks(event string, hooks []func(Task), task Task) {
+	for _, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("Task hook panicked", "event", event, "taskId", task.ID, "panic", r)
+				}
+			}()
+			hook(task)
+		}()
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// recordDeletes registers an OnDelete hook and returns the ids it has seen.
+func recordDeletes(application *App) *[]int64 {
+	var deleted []int64
+	application.hooks.OnDelete = append(application.hooks.OnDelete, func(task Task) {
+		deleted = append(deleted, task.ID)
+	})
+	return &deleted
+}
+
+func TestMergeRunsDeleteHook(t *testing.T) {
+	application := newTestApp(t)
+	deleted := recordDeletes(application)
+	source, _ := application.createTask("source", 0, defaultListID)
+	target, _ := application.createTask("target", 0, defaultListID)
+
+	recorder := application.serve(postForm("/mergeTasks", mergeForm(source, target)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	if !slices.Equal(*deleted, []int64{source.ID}) {
+		t.Errorf("delete hook saw %v, want [%d]", *deleted, source.ID)
+	}
+}
+
+func TestRetentionRunsDeleteHook(t *testing.T) {
+	application := newTestApp(t)
+	deleted := recordDeletes(application)
+	old, _ := application.createTask("old", 0, defaultListID)
+	recent, _ := application.createTask("recent", 0, defaultListID)
+	_, err := application.db.Exec(`UPDATE tasks SET completed = 1,
+		completed_at = CASE id WHEN ? THEN datetime('now', '-48 hours') ELSE datetime('now') END`, old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := application.deleteCompletedBefore(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d tasks, want 1", removed)
+	}
+	if !slices.Equal(*deleted, []int64{old.ID}) {
+		t.Errorf("delete hook saw %v, want [%d], not the recent task %d", *deleted, old.ID, recent.ID)
+	}
+}
+
+func TestCascadeListDeleteRunsDeleteHook(t *testing.T) {
+	application := newTestApp(t, "-cascade-list-delete")
+	deleted := recordDeletes(application)
+	result, err := application.db.Exec("INSERT INTO lists (name) VALUES ('Errands')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listID, _ := result.LastInsertId()
+	first, _ := application.createTask("first", 0, listID)
+	second, _ := application.createTask("second", 0, listID)
+	application.createTask("elsewhere", 0, defaultListID)
+
+	recorder := application.serve(postForm("/deleteList", url.Values{"listId": {strconv.FormatInt(listID, 10)}}))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	slices.Sort(*deleted)
+	if !slices.Equal(*deleted, []int64{first.ID, second.ID}) {
+		t.Errorf("delete hook saw %v, want [%d %d]", *deleted, first.ID, second.ID)
+	}
+}
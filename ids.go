@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// The obfuscation is a fixed bijection on uint64 (xor, then multiply by an
+// odd constant), so ids round-trip without a lookup table. It hides how
+// many tasks exist but is not meant as a security boundary.
+const (
+	idMask       uint64 = 0x5bd1e9955bd1e995
+	idMultiplier uint64 = 0x9e3779b97f4a7c15
+)
+
+var (
+	errInvalidTaskID = errors.New("invalid task id")
+
+	idInverse = multiplicativeInverse(idMultiplier)
+)
+
+// idCodec converts task ids to and from the identifiers used in templates
+// and form values. When obfuscate is false, plain decimal ids are used.
+type idCodec struct {
+	obfuscate bool
+}
+
+func (codec idCodec) encode(id int64) string {
+	if !codec.obfuscate {
+		return formatInt(uint64(id), 10)
+	}
+	return formatInt((uint64(id)^idMask)*idMultiplier, 62)
+}
+
+func (codec idCodec) decode(value string) (int64, error) {
+	var id uint64
+	if !codec.obfuscate {
+		parsed, ok := parseDigits(value, 10)
+		if !ok {
+			return 0, errInvalidTaskID
+		}
+		id = parsed
+	} else {
+		encoded, ok := parseDigits(value, 62)
+		if !ok {
+			return 0, errInvalidTaskID
+		}
+		id = (encoded * idInverse) ^ idMask
+	}
+	if id == 0 || id > math.MaxInt64 {
+		return 0, errInvalidTaskID
+	}
+	return int64(id), nil
+}
+
+// formatInt formats value in the given base using base62Alphabet.
+func formatInt(value uint64, base uint64) string {
+	if value == 0 {
+		return "0"
+	}
+	var digits []byte
+	for value > 0 {
+		digits = append(digits, base62Alphabet[value%base])
+		value /= base
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+	return string(digits)
+}
+
+// parseDigits parses value in the given base using base62Alphabet, rejecting
+// empty input, unknown characters and overflow.
+func parseDigits(value string, base uint64) (uint64, bool) {
+	if value == "" || len(value) > 20 {
+		return 0, false
+	}
+	var result uint64
+	for _, char := range value {
+		digit := strings.IndexRune(base62Alphabet, char)
+		if digit < 0 || uint64(digit) >= base {
+			return 0, false
+		}
+		if result > (math.MaxUint64-uint64(digit))/base {
+			return 0, false
+		}
+		result = result*base + uint64(digit)
+	}
+	return result, true
+}
+
+// multiplicativeInverse returns the inverse of an odd number modulo 2^64
+// using Newton's iteration; each step doubles the number of correct bits.
+func multiplicativeInverse(odd uint64) uint64 {
+	inverse := odd
+	for i := 0; i < 5; i++ {
+		inverse *= 2 - odd*inverse
+	}
+	return inverse
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportBytes bounds the size of an uploaded CSV file.
+const maxImportBytes = 4 << 20
+
+// Import conflict strategies, chosen with the onConflict parameter, for rows
+// whose text matches a task already in the list.
+const (
+	// importDuplicate inserts the row as a new task anyway.
+	importDuplicate = "duplicate"
+	// importSkip leaves the existing task untouched.
+	importSkip = "skip"
+	// importUpdate overwrites the existing task's fields from the row.
+	importUpdate = "update"
+)
+
+// ImportResult is the body of a POST /import.csv response. When Errors is
+// not empty nothing was imported.
+type ImportResult struct {
+	Inserted int           `json:"inserted"`
+	Updated  int           `json:"updated"`
+	Skipped  int           `json:"skipped"`
+	Errors   []ImportError `json:"errors"`
+	// IgnoredColumns are header columns that do not map to a task field.
+	IgnoredColumns []string `json:"ignoredColumns"`
+}
+
+// ImportError is a problem with one line of an imported file.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// importedTask is a parsed CSV row waiting to be inserted.
+type importedTask struct {
+	text      string
+	completed bool
+}
+
+// importedChanges is what storing an import changed, for the response and
+// the hooks.
+type importedChanges struct {
+	created   []Task
+	completed []Task
+	updated   int
+	skipped   int
+}
+
+// ImportCSV adds the tasks in a CSV file to the list given by listId. The
+// header row names the columns, in any order: task is required and
+// completed is optional. Every row is checked before anything is stored,
+// and the rows are then stored in one transaction. onConflict says what to
+// do with rows whose text matches a task already in the list: duplicate
+// (the default), skip or update.
+func (application *App) ImportCSV(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.URL.Query().Get("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	strategy := request.URL.Query().Get("onConflict")
+	switch strategy {
+	case "":
+		strategy = importDuplicate
+	case importDuplicate, importSkip, importUpdate:
+	default:
+		http.Error(response, "onConflict must be duplicate, skip or update", http.StatusBadRequest)
+		return
+	}
+
+	reader := csv.NewReader(http.MaxBytesReader(response, request.Body, maxImportBytes))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		http.Error(response, "CSV file is empty", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(response, "Error parsing CSV: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := ImportResult{Errors: []ImportError{}, IgnoredColumns: []string{}}
+	taskColumn, completedColumn := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "task":
+			taskColumn = i
+		case "completed":
+			completedColumn = i
+		default:
+			result.IgnoredColumns = append(result.IgnoredColumns, name)
+		}
+	}
+	if taskColumn < 0 {
+		http.Error(response, "CSV header must have a task column", http.StatusBadRequest)
+		return
+	}
+
+	var tasks []importedTask
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			result.Errors = append(result.Errors, ImportError{Line: parseErr.Line, Error: "wrong number of fields"})
+			continue
+		}
+		if errors.As(err, &parseErr) {
+			// The reader cannot resynchronise after a quoting error
+			result.Errors = append(result.Errors, ImportError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			break
+		}
+		if err != nil {
+			http.Error(response, "Error reading CSV: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		task, err := application.parseImportedTask(record, taskColumn, completedColumn)
+		if err != nil {
+			result.Errors = append(result.Errors, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	if len(result.Errors) > 0 {
+		writeJSON(response, http.StatusBadRequest, result)
+		return
+	}
+
+	application.mu.Lock()
+	changes, err := application.storeImportedTasks(listID, tasks, strategy)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error importing tasks", err)
+		return
+	}
+	for _, task := range changes.created {
+		runHooks("create", application.hooks.OnCreate, task)
+	}
+	for _, task := range changes.completed {
+		runHooks("complete", application.hooks.OnComplete, task)
+	}
+	result.Inserted = len(changes.created)
+	result.Updated = changes.updated
+	result.Skipped = changes.skipped
+	writeJSON(response, http.StatusOK, result)
+}
+
+// parseImportedTask validates one CSV record the same way AddTask
+// validates a form.
+func (application *App) parseImportedTask(record []string, taskColumn, completedColumn int) (importedTask, error) {
+	var task importedTask
+	task.text = application.normalize(record[taskColumn])
+	if task.text == "" {
+		return task, errors.New("task cannot be empty")
+	}
+	if application.containsBlockedWord(task.text) {
+		return task, errors.New("task contains a blocked word")
+	}
+	if completedColumn >= 0 {
+		if value := strings.TrimSpace(record[completedColumn]); value != "" {
+			completed, err := strconv.ParseBool(value)
+			if err != nil {
+				return task, errors.New("completed must be true or false")
+			}
+			task.completed = completed
+		}
+	}
+	return task, nil
+}
+
+// storeImportedTasks stores tasks in the list in one transaction, resolving
+// rows that match an existing task by text with strategy. The match is
+// against the list as the import proceeds, so repeated rows in one file
+// are resolved the same way. The caller must hold the mutex.
+func (application *App) storeImportedTasks(listID int64, tasks []importedTask, strategy string) (importedChanges, error) {
+	var changes importedChanges
+	tx, err := application.db.Begin()
+	if err != nil {
+		return changes, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM lists WHERE id = ?)", listID).Scan(&exists); err != nil {
+		return changes, err
+	}
+	if !exists {
+		return changes, errListNotFound
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO tasks (task, completed, completed_at, list_id)
+		VALUES (?, ?, CASE WHEN ? THEN CURRENT_TIMESTAMP END, ?)
+		RETURNING ` + taskColumns)
+	if err != nil {
+		return changes, err
+	}
+	defer insert.Close()
+	match, err := tx.Prepare("SELECT id, completed FROM tasks WHERE list_id = ? AND task = ? ORDER BY id LIMIT 1")
+	if err != nil {
+		return changes, err
+	}
+	defer match.Close()
+
+	pending, completed := 0, 0
+	for _, task := range tasks {
+		if strategy != importDuplicate {
+			var id int64
+			var done bool
+			err := match.QueryRow(listID, task.text).Scan(&id, &done)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return changes, err
+			}
+			if err == nil && strategy == importSkip {
+				changes.skipped++
+				continue
+			}
+			if err == nil {
+				changes.updated++
+				if done == task.completed {
+					continue
+				}
+				updated, err := scanTask(tx.QueryRow(`UPDATE tasks
+					SET completed = ?, completed_at = CASE WHEN ? THEN CURRENT_TIMESTAMP END
+					WHERE id = ?
+					RETURNING `+taskColumns, task.completed, task.completed, id))
+				if err != nil {
+					return changes, err
+				}
+				if task.completed {
+					pending, completed = pending-1, completed+1
+					changes.completed = append(changes.completed, updated)
+				} else {
+					pending, completed = pending+1, completed-1
+				}
+				continue
+			}
+		}
+
+		stored, err := scanTask(insert.QueryRow(task.text, task.completed, task.completed, listID))
+		if err != nil {
+			return changes, err
+		}
+		changes.created = append(changes.created, stored)
+		if task.completed {
+			completed++
+		} else {
+			pending++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return changes, err
+	}
+	application.adjustCounts(pending, completed)
+	return changes, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func importCSV(t *testing.T, application *App, strategy, body string) (int, ImportResult) {
+	t.Helper()
+	target := "/import.csv"
+	if strategy != "" {
+		target += "?onConflict=" + strategy
+	}
+	recorder := application.serve(httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	var result ImportResult
+	if recorder.Code == http.StatusOK || recorder.Code == http.StatusBadRequest {
+		decodeJSON(t, recorder, &result)
+	}
+	return recorder.Code, result
+}
+
+func TestImportCSVConflictStrategies(t *testing.T) {
+	const first = "task,completed\nBuy milk,false\nWalk dog,true\n"
+	const second = "task,completed\nBuy milk,true\nWalk dog,true\nNew task,\n"
+
+	tests := []struct {
+		strategy                   string
+		inserted, updated, skipped int
+		pending, completed         int
+	}{
+		{"", 3, 0, 0, 2, 3},
+		{importDuplicate, 3, 0, 0, 2, 3},
+		{importSkip, 1, 0, 2, 2, 1},
+		{importUpdate, 1, 2, 0, 1, 2},
+	}
+	for _, test := range tests {
+		name := test.strategy
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			application := newTestApp(t)
+			if code, result := importCSV(t, application, test.strategy, first); code != http.StatusOK || result.Inserted != 2 {
+				t.Fatalf("first import: status %d, result %+v", code, result)
+			}
+
+			code, result := importCSV(t, application, test.strategy, second)
+			if code != http.StatusOK {
+				t.Fatalf("second import: status %d, want 200", code)
+			}
+			if result.Inserted != test.inserted || result.Updated != test.updated || result.Skipped != test.skipped {
+				t.Errorf("second import inserted %d, updated %d, skipped %d; want %d, %d, %d",
+					result.Inserted, result.Updated, result.Skipped, test.inserted, test.updated, test.skipped)
+			}
+
+			var stats struct {
+				Pending   int `json:"pending"`
+				Completed int `json:"completed"`
+			}
+			decodeJSON(t, application.serve(httptest.NewRequest(http.MethodGet, "/stats", nil)), &stats)
+			if stats.Pending != test.pending || stats.Completed != test.completed {
+				t.Errorf("/stats reports %d pending and %d completed, want %d and %d",
+					stats.Pending, stats.Completed, test.pending, test.completed)
+			}
+
+			var pending, completed int
+			err := application.db.QueryRow("SELECT COUNT(*) FILTER (WHERE completed = 0), COUNT(*) FILTER (WHERE completed = 1) FROM tasks").Scan(&pending, &completed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pending != stats.Pending || completed != stats.Completed {
+				t.Errorf("cached counts %d/%d differ from the table's %d/%d", stats.Pending, stats.Completed, pending, completed)
+			}
+		})
+	}
+}
+
+func TestImportCSVReportsErrorLines(t *testing.T) {
+	application := newTestApp(t)
+	body := "task,completed\n" +
+		"Fine,true\n" +
+		"Bad,maybe\n" +
+		"\"Spans\ntwo lines\",false\n" +
+		"Also bad,nope\n" +
+		"Too,many,fields\n"
+
+	code, result := importCSV(t, application, "", body)
+	if code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", code)
+	}
+	var lines []int
+	for _, importErr := range result.Errors {
+		lines = append(lines, importErr.Line)
+	}
+	if want := []int{3, 6, 7}; !slices.Equal(lines, want) {
+		t.Errorf("errors on lines %v, want %v: %+v", lines, want, result.Errors)
+	}
+	if len(result.Errors) > 0 && result.Errors[0].Error != "completed must be true or false" {
+		t.Errorf("error %q for a bad completed value", result.Errors[0].Error)
+	}
+
+	var count int
+	if err := application.db.QueryRow("SELECT COUNT(*) FROM tasks").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d tasks stored from a file with errors, want none", count)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+)
+
+// IntegrityReport is the body of GET /admin/integrity.
+type IntegrityReport struct {
+	OK bool `json:"ok"`
+	// Integrity holds the rows of PRAGMA integrity_check, which is just
+	// "ok" for a healthy database.
+	Integrity []string `json:"integrity"`
+	// ForeignKeys lists rows whose foreign keys point at missing parents.
+	ForeignKeys []ForeignKeyProblem `json:"foreignKeys"`
+}
+
+// ForeignKeyProblem is one row of PRAGMA foreign_key_check.
+type ForeignKeyProblem struct {
+	Table  string `json:"table"`
+	RowID  *int64 `json:"rowId"`
+	Parent string `json:"parent"`
+}
+
+// CheckIntegrity runs SQLite's integrity and foreign key checks and reports
+// the problems they find. The checks only read, and the mutex is not held
+// so task requests keep being served while they run.
+func (application *App) CheckIntegrity(response http.ResponseWriter, request *http.Request) {
+	report := IntegrityReport{Integrity: []string{}, ForeignKeys: []ForeignKeyProblem{}}
+
+	rows, err := application.query("PRAGMA integrity_check")
+	if err != nil {
+		dbError(response, "Error checking integrity", err)
+		return
+	}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			rows.Close()
+			dbError(response, "Error checking integrity", err)
+			return
+		}
+		report.Integrity = append(report.Integrity, line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		dbError(response, "Error checking integrity", err)
+		return
+	}
+
+	rows, err = application.query("PRAGMA foreign_key_check")
+	if err != nil {
+		dbError(response, "Error checking foreign keys", err)
+		return
+	}
+	for rows.Next() {
+		var problem ForeignKeyProblem
+		var rowID sql.NullInt64
+		var foreignKey int
+		if err := rows.Scan(&problem.Table, &rowID, &problem.Parent, &foreignKey); err != nil {
+			rows.Close()
+			dbError(response, "Error checking foreign keys", err)
+			return
+		}
+		if rowID.Valid {
+			problem.RowID = &rowID.Int64
+		}
+		report.ForeignKeys = append(report.ForeignKeys, problem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		dbError(response, "Error checking foreign keys", err)
+		return
+	}
+
+	report.OK = len(report.Integrity) == 1 && report.Integrity[0] == "ok" && len(report.ForeignKeys) == 0
+	writeJSON(response, http.StatusOK, report)
+}
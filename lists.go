@@ -0,0 +1,221 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultListID is the list created by the lists migration. Tasks that
+// existed before lists were introduced belong to it, requests without a
+// listId use it, and it cannot be deleted.
+const defaultListID int64 = 1
+
+// TaskList is a named group of tasks, such as Work or Home.
+type TaskList struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+var (
+	errListNotEmpty = errors.New("list still has tasks")
+	errListNotFound = errors.New("list not found")
+)
+
+// parseListID reads a listId parameter, falling back to the default list
+// when it is empty.
+func parseListID(value string) (int64, error) {
+	if value == "" {
+		return defaultListID, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid list id")
+	}
+	return id, nil
+}
+
+// queryLists returns every list, the default list first. The caller must
+// hold the mutex.
+func (application *App) queryLists() ([]TaskList, error) {
+	rows, err := application.query("SELECT id, name FROM lists ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []TaskList{}
+	for rows.Next() {
+		var list TaskList
+		if err := rows.Scan(&list.ID, &list.Name); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// renderListPicker renders the listPicker template with selected chosen.
+// The caller must hold the mutex.
+func (application *App) renderListPicker(response http.ResponseWriter, selected int64) {
+	lists, err := application.queryLists()
+	if err != nil {
+		dbError(response, "Error fetching lists", err)
+		return
+	}
+
+	data := application.newViewData()
+	data.Lists = lists
+	data.ListID = selected
+	application.render(response, "listPicker", data)
+}
+
+// GetLists renders the list picker with the listId parameter selected.
+func (application *App) GetLists(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+	application.renderListPicker(response, listID)
+}
+
+// CreateList adds a list with the given name and renders the list picker
+// with it selected.
+func (application *App) CreateList(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.FormValue("name"))
+	if name == "" {
+		http.Error(response, "List name cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	result, err := application.exec("INSERT INTO lists (name) VALUES (?)", name)
+	if err != nil {
+		dbError(response, "Error creating list", err)
+		return
+	}
+	listID, err := result.LastInsertId()
+	if err != nil {
+		dbError(response, "Error creating list", err)
+		return
+	}
+	application.renderListPicker(response, listID)
+}
+
+// DeleteList removes a list and renders the list picker with the default
+// list selected. A list that still has tasks is only deleted, together with
+// its tasks, when -cascade-list-delete is set; otherwise it is a 409.
+func (application *App) DeleteList(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	if listID == defaultListID {
+		http.Error(response, "The default list cannot be deleted", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	deleted, err := application.deleteList(listID, application.config.CascadeListDelete)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errListNotEmpty) {
+		http.Error(response, "List still has tasks", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error deleting list", err)
+		return
+	}
+	for _, task := range deleted {
+		runHooks("delete", application.hooks.OnDelete, task)
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+	application.renderListPicker(response, defaultListID)
+}
+
+// deleteList removes the list in one transaction, deleting its tasks first
+// when cascade is set, and returns the deleted tasks. The caller must hold
+// the mutex.
+func (application *App) deleteList(listID int64, cascade bool) ([]Task, error) {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var tasks int
+	err = tx.QueryRow("SELECT COUNT(*) FROM tasks WHERE list_id = ?", listID).Scan(&tasks)
+	if err != nil {
+		return nil, err
+	}
+	if tasks > 0 && !cascade {
+		return nil, errListNotEmpty
+	}
+
+	rows, err := tx.Query("DELETE FROM tasks WHERE list_id = ? RETURNING "+taskColumns, listID)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := scanTasks(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec("DELETE FROM lists WHERE id = ?", listID)
+	if err != nil {
+		return nil, err
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if removed == 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if len(deleted) > 0 {
+		application.counts.stale = true
+		application.streak.valid = false
+	}
+	return deleted, nil
+}
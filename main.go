@@ -1,15 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"embed"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Embed the frontend directory
@@ -18,20 +32,125 @@ import (
 var assets embed.FS
 
 type Task struct {
-	ID        int64
-	Task      string
-	Completed bool
+	ID              int64  `json:"id"`
+	Task            string `json:"task"`
+	Completed       bool   `json:"completed"`
+	EstimateMinutes int    `json:"estimateMinutes"`
+	ActualMinutes   int    `json:"actualMinutes"`
+	ListID          int64  `json:"listId"`
+	// CompletedAt is when the task was last completed, and is null while it
+	// is pending or was completed before completion times were recorded.
+	CompletedAt nullTime `json:"completedAt"`
+	// Blocked means the task depends on tasks that are not yet completed.
+	// It is only filled in for rendered task lists.
+	Blocked bool `json:"blocked,omitempty"`
+}
+
+// viewData is the data passed to the index and taskList templates.
+type viewData struct {
+	Tasks    []Task
+	ReadOnly bool
+	// CompletedInline means the main list also shows completed tasks, so
+	// changing one should re-render the main list rather than the
+	// completed view.
+	CompletedInline bool
+	// PendingCount and Streak are only set for the index page, where they
+	// are shown in the title and header.
+	PendingCount int
+	Streak       int
+
+	// Lists and ListID drive the list picker; ListID is the list shown.
+	Lists  []TaskList
+	ListID int64
+	// Features are the optional features enabled with -features.
+	Features featureSet
+	// Highlight is the search query whose matches are marked in task text.
+	Highlight string
+	// PollInterval is how often the index page reloads the task list; zero
+	// disables polling.
+	PollInterval time.Duration
+
+	// Dev enables debugging output. GeneratedAt and LastModified are set
+	// when rendering task lists so stale renders can be told apart.
+	Dev          bool
+	GeneratedAt  time.Time
+	LastModified time.Time
 }
 
 type App struct {
-	mu        sync.Mutex
-	db        *sql.DB
-	templates *template.Template
+	mu         sync.Mutex
+	config     Config
+	db         *sql.DB
+	templates  *template.Template
+	static     map[string]staticFile
+	normalizer Normalizer
+	blocklist  map[string]struct{}
+	fts        bool
+	ids        idCodec
+
+	slowQueries *slowQueryLog
+
+	maintenance  bool
+	lastModified time.Time
+	counts       taskCounts
+	streak       streakCache
+
+	hooks Hooks
+
+	deleteToken deleteToken
+}
+
+// NewApp builds an App from cfg: it loads the blocklist, parses the
+// templates and opens and migrates the database.
+func NewApp(cfg Config) (*App, error) {
+	application := &App{
+		config:       cfg,
+		ids:          idCodec{obfuscate: cfg.ObfuscateIDs},
+		slowQueries:  &slowQueryLog{threshold: cfg.SlowQueryThreshold},
+		lastModified: time.Now(),
+	}
+
+	normalizers := []Normalizer{collapseWhitespace}
+	if cfg.NormalizeUnicode {
+		normalizers = append(normalizers, normalizeNFC)
+	}
+	application.normalizer = chainNormalizers(normalizers...)
+
+	if cfg.BlocklistFile != "" {
+		blocklist, err := loadBlocklist(cfg.BlocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading blocklist: %w", err)
+		}
+		application.blocklist = blocklist
+	}
+
+	// An API-only server never renders HTML, so it skips the templates
+	// and assets entirely
+	if !cfg.APIOnly {
+		tmpl, err := parseTemplates(assets, application.templateFuncs())
+		if err != nil {
+			return nil, fmt.Errorf("parsing templates: %w", err)
+		}
+		application.templates = tmpl
+
+		application.static, err = loadStaticFiles(assets, "frontend")
+		if err != nil {
+			return nil, fmt.Errorf("loading static files: %w", err)
+		}
+	}
+
+	if err := application.initializeDB(); err != nil {
+		if application.db != nil {
+			application.db.Close()
+		}
+		return nil, fmt.Errorf("initializing database: %w", err)
+	}
+	return application, nil
 }
 
 func (application *App) initializeDB() error {
 	var err error
-	application.db, err = sql.Open("sqlite3", "./tasks.db")
+	application.db, err = sql.Open(sqliteDriver, application.config.DBPath)
 	if err != nil {
 		return err
 	}
@@ -41,12 +160,108 @@ func (application *App) initializeDB() error {
 		task TEXT NOT NULL,
 		completed BOOLEAN NOT NULL DEFAULT 0
 	)`)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := application.migrate(); err != nil {
+		return err
+	}
+	if err := application.setupSearch(); err != nil {
+		return err
+	}
+	return application.refreshCounts()
+}
+
+// migrations are applied in order on startup. Each one runs exactly once;
+// its 1-based index is recorded in schema_migrations. Only ever append.
+var migrations = []string{
+	// Tasks completed before this migration keep a NULL completed_at: when
+	// they were completed is not known, and the migration time would count
+	// them as done today in the stats and the streak.
+	`ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMP`,
+	`ALTER TABLE tasks ADD COLUMN estimate_minutes INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE tasks ADD COLUMN actual_minutes INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE tasks ADD COLUMN position INTEGER NOT NULL DEFAULT 0`,
+	// updated_at and the tombstones back incremental sync. Triggers keep
+	// them current for every write path, at millisecond precision.
+	`ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMP;
+	UPDATE tasks SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now');
+	CREATE TRIGGER tasks_created AFTER INSERT ON tasks BEGIN
+		UPDATE tasks SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = new.id;
+	END;
+	CREATE TRIGGER tasks_touched AFTER UPDATE ON tasks WHEN new.updated_at IS old.updated_at BEGIN
+		UPDATE tasks SET updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE id = new.id;
+	END;
+	CREATE TABLE task_tombstones (
+		id INTEGER PRIMARY KEY,
+		deleted_at TIMESTAMP NOT NULL
+	);
+	CREATE TRIGGER tasks_deleted AFTER DELETE ON tasks BEGIN
+		INSERT OR REPLACE INTO task_tombstones (id, deleted_at) VALUES (old.id, strftime('%Y-%m-%d %H:%M:%f', 'now'));
+	END`,
+	`CREATE TABLE lists (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);
+	INSERT INTO lists (id, name) VALUES (1, 'default');
+	ALTER TABLE tasks ADD COLUMN list_id INTEGER NOT NULL DEFAULT 1;
+	CREATE INDEX tasks_list_id ON tasks (list_id)`,
+	// Foreign keys are not enforced, so a trigger drops the dependencies of
+	// deleted tasks
+	`CREATE TABLE task_dependencies (
+		task_id INTEGER NOT NULL REFERENCES tasks (id),
+		depends_on_id INTEGER NOT NULL REFERENCES tasks (id),
+		PRIMARY KEY (task_id, depends_on_id)
+	);
+	CREATE INDEX task_dependencies_depends_on_id ON task_dependencies (depends_on_id);
+	CREATE TRIGGER task_dependencies_deleted AFTER DELETE ON tasks BEGIN
+		DELETE FROM task_dependencies WHERE task_id = old.id OR depends_on_id = old.id;
+	END`,
+	`CREATE TABLE task_presets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task TEXT NOT NULL,
+		estimate_minutes INTEGER NOT NULL DEFAULT 0
+	)`,
+}
+
+func (application *App) migrate() error {
+	_, err := application.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	err = application.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := application.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (application *App) AddTask(response http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodPost {
-		http.Error(response, "Invalid request method", http.StatusMethodNotAllowed)
+		application.methodNotAllowed(response, request)
 		return
 	}
 
@@ -56,38 +271,144 @@ func (application *App) AddTask(response http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	task := request.FormValue("task")
+	task := application.normalize(request.FormValue("task"))
 	if task == "" {
 		http.Error(response, "Task cannot be empty", http.StatusBadRequest)
 		return
 	}
+	if application.containsBlockedWord(task) {
+		http.Error(response, "Task contains a blocked word", http.StatusBadRequest)
+		return
+	}
 
-	application.mu.Lock()
-	_, err = application.db.Exec("INSERT INTO tasks (task) VALUES (?)", task)
-	application.mu.Unlock()
+	estimate, err := parseMinutes(request.FormValue("estimate"))
+	if err != nil {
+		http.Error(response, "Invalid estimate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
 
+	created, err := application.createTask(task, estimate, listID)
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(response, "Error adding task: "+err.Error(), http.StatusInternalServerError)
+		dbError(response, "Error adding task", err)
 		return
 	}
 
+	// Lets clients match the re-rendered list to the task they added
+	response.Header().Set("X-Created-Task-ID", application.ids.encode(created.ID))
 	// Only render the task list template after successful insertion
-	application.renderTasks(response, false)
+	application.renderTasks(response, listID, false)
+}
+
+// createTask stores a new pending task in the given list and runs the
+// OnCreate hooks. It returns errListNotFound when the list does not exist.
+func (application *App) createTask(text string, estimate int, listID int64) (Task, error) {
+	application.mu.Lock()
+	// Selecting from lists makes the insert a no-op for an unknown list
+	created, err := scanTask(application.queryRow(`INSERT INTO tasks (task, estimate_minutes, list_id)
+		SELECT ?, ?, id FROM lists WHERE id = ?
+		RETURNING `+taskColumns, text, estimate, listID))
+	if errors.Is(err, sql.ErrNoRows) {
+		application.mu.Unlock()
+		return Task{}, errListNotFound
+	}
+	application.recordWrite(err)
+	if err == nil {
+		application.adjustCounts(1, 0)
+	}
+	application.mu.Unlock()
+
+	if err != nil {
+		return Task{}, err
+	}
+	runHooks("create", application.hooks.OnCreate, created)
+	return created, nil
 }
 
 func (application *App) GetTasks(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetTasks called")
-	application.renderTasks(w, false)
+	slog.Debug("GetTasks called")
+	listID, err := parseListID(r.FormValue("listId"))
+	if err != nil {
+		http.Error(w, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	application.renderTasks(w, listID, false)
 }
 
 func (application *App) GetCompletedTasks(response http.ResponseWriter, request *http.Request) {
-	fmt.Println("GetCompletedTasks called")
-	application.renderTasks(response, true)
+	slog.Debug("GetCompletedTasks called")
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	application.renderTasks(response, listID, true)
+}
+
+// GetCompletedToday renders the tasks in a list completed since midnight in
+// the configured timezone.
+func (application *App) GetCompletedToday(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	start, end := dayBounds(time.Now(), application.config.Location)
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+	application.renderTaskQuery(response, `SELECT `+taskColumns+` FROM tasks
+		WHERE list_id = ? AND completed = 1 AND completed_at >= ? AND completed_at < ?
+		ORDER BY completed_at DESC`, listID, sqliteTime(start), sqliteTime(end))
+}
+
+const (
+	defaultFocusTasks = 3
+	maxFocusTasks     = 20
+)
+
+// GetFocus renders only the first n pending tasks of a list, in the list's
+// own order, leaving out tasks still blocked by their dependencies. n
+// defaults to 3 and is clamped to between 1 and 20.
+func (application *App) GetFocus(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	limit := defaultFocusTasks
+	if value := request.FormValue("n"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(response, "Invalid n: must be an integer", http.StatusBadRequest)
+			return
+		}
+		limit = max(1, min(limit, maxFocusTasks))
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+	application.renderTaskQuery(response, `SELECT `+taskColumns+` FROM tasks
+		WHERE list_id = ? AND completed = 0 AND NOT EXISTS (
+			SELECT 1 FROM task_dependencies
+			JOIN tasks AS dependency ON dependency.id = task_dependencies.depends_on_id
+			WHERE task_dependencies.task_id = tasks.id AND dependency.completed = 0
+		)
+		ORDER BY position, id DESC
+		LIMIT ?`, listID, limit)
 }
 
 func (application *App) CompleteTask(response http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodPost {
-		http.Error(response, "Invalid request method", http.StatusMethodNotAllowed)
+		application.methodNotAllowed(response, request)
 		return
 	}
 
@@ -97,75 +418,230 @@ func (application *App) CompleteTask(response http.ResponseWriter, request *http
 		return
 	}
 
-	taskID := request.FormValue("taskId")
+	taskID, err := application.ids.decode(request.FormValue("taskId"))
+	if err != nil {
+		http.Error(response, "Invalid task id", http.StatusBadRequest)
+		return
+	}
 	isCompleted := request.FormValue("completed")
 	showCompleted := request.FormValue("showCompleted")
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
 
-	fmt.Printf("TaskID: %s, Completing: %s, ShowCompleted: %s\n", taskID, isCompleted, showCompleted)
+	slog.Debug("CompleteTask called", "taskId", taskID, "completed", isCompleted, "showCompleted", showCompleted)
 
 	completed := isCompleted == "true"
 
 	application.mu.Lock()
-	_, err = application.db.Exec("UPDATE tasks SET completed = ? WHERE id = ?", completed, taskID)
+	if completed && request.FormValue("force") != "true" {
+		blocked, err := application.isBlocked(taskID)
+		if err != nil {
+			application.mu.Unlock()
+			dbError(response, "Error checking dependencies", err)
+			return
+		}
+		if blocked {
+			application.mu.Unlock()
+			http.Error(response, "Task is blocked by incomplete tasks", http.StatusConflict)
+			return
+		}
+	}
+	// Tasks already in the requested state are left alone, so the number of
+	// changed rows says how the counts move
+	updated, err := scanTask(application.queryRow(`UPDATE tasks
+		SET completed = ?, completed_at = CASE WHEN ? THEN CURRENT_TIMESTAMP END
+		WHERE id = ? AND completed != ?
+		RETURNING `+taskColumns, completed, completed, taskID, completed))
+	changed := err == nil
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	application.recordWrite(err)
+	if changed && completed {
+		application.adjustCounts(-1, 1)
+	} else if changed {
+		application.adjustCounts(1, -1)
+	}
 	application.mu.Unlock()
 
 	if err != nil {
-		http.Error(response, "Error updating task: "+err.Error(), http.StatusInternalServerError)
+		dbError(response, "Error updating task", err)
 		return
 	}
+	if changed && completed {
+		runHooks("complete", application.hooks.OnComplete, updated)
+	}
 
 	// Show the same list we were viewing (completed or uncompleted)
-	application.renderTasks(response, showCompleted == "true")
+	application.renderTasks(response, listID, showCompleted == "true")
 }
 
 // Add Mutex for Safety
-func (application *App) renderTasks(response http.ResponseWriter, completed bool) {
+func (application *App) renderTasks(response http.ResponseWriter, listID int64, completed bool) {
 	application.mu.Lock()
 	defer application.mu.Unlock()
 
-	var rows *sql.Rows
-	var err error
 	if completed {
-		rows, err = application.db.Query("SELECT id, task, completed FROM tasks WHERE completed = 1 ORDER BY id DESC")
+		application.renderTaskQuery(response, "SELECT "+taskColumns+" FROM tasks WHERE list_id = ? AND completed = 1 ORDER BY position, id DESC", listID)
+	} else if application.config.CompletedInline {
+		// Pending tasks stay on top, completed ones follow struck through
+		application.renderTaskQuery(response, "SELECT "+taskColumns+" FROM tasks WHERE list_id = ? ORDER BY completed, position, id DESC", listID)
 	} else {
-		rows, err = application.db.Query("SELECT id, task, completed FROM tasks WHERE completed = 0 ORDER BY id DESC")
+		application.renderTaskQuery(response, "SELECT "+taskColumns+" FROM tasks WHERE list_id = ? AND completed = 0 ORDER BY position, id DESC", listID)
 	}
+}
+
+// renderTaskQuery renders the taskList template with the tasks returned by
+// query. The caller must hold the mutex.
+func (application *App) renderTaskQuery(response http.ResponseWriter, query string, args ...any) {
+	application.renderTaskQueryData(response, application.newViewData(), query, args...)
+}
+
+// renderTaskQueryData is renderTaskQuery starting from data instead of a
+// fresh viewData. The caller must hold the mutex.
+func (application *App) renderTaskQueryData(response http.ResponseWriter, data viewData, query string, args ...any) {
+	rows, err := application.query(query, args...)
 	if err != nil {
-		http.Error(response, "Error fetching tasks: "+err.Error(), http.StatusInternalServerError)
+		dbError(response, "Error fetching tasks", err)
 		return
 	}
 	defer rows.Close()
 
-	var tasks []Task
-	for rows.Next() {
-		var task Task
-		if err := rows.Scan(&task.ID, &task.Task, &task.Completed); err != nil {
-			http.Error(response, "Error scanning task: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		tasks = append(tasks, task)
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		// Rendering what was read so far would pass off a partial list as
+		// the whole one
+		slog.Error("Error reading tasks", "error", err)
+		dbError(response, "Error scanning task", err)
+		return
+	}
+	if err := application.markBlocked(tasks); err != nil {
+		dbError(response, "Error checking dependencies", err)
+		return
 	}
 
-	err = application.templates.ExecuteTemplate(response, "taskList", tasks)
-	if err != nil {
+	data.Tasks = tasks
+	data.GeneratedAt = time.Now()
+	data.LastModified = application.lastModified
+	application.render(response, "taskList", data)
+}
+
+// render executes the named template into response with a 200. It replies
+// with a 500 instead of panicking when the App was built without templates.
+func (application *App) render(response http.ResponseWriter, name string, data any) {
+	if application.templates == nil {
+		http.Error(response, "Error rendering template: no templates loaded", http.StatusInternalServerError)
+		return
+	}
+	if err := application.writeTemplate(response, http.StatusOK, name, data); err != nil {
 		http.Error(response, "Error rendering template: "+err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// writeTemplate executes the named template into a buffer and only writes
+// it, with status, once execution succeeded. A failing template therefore
+// leaves the response untouched for the caller to report the error.
+func (application *App) writeTemplate(response http.ResponseWriter, status int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := application.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+
+	header := response.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	header.Set("Content-Length", strconv.Itoa(buf.Len()))
+	response.WriteHeader(status)
+	_, err := buf.WriteTo(response)
+	if err != nil {
+		// The status is already sent, so the client just sees a short body
+		slog.Debug("Error writing response", "template", name, "error", err)
+	}
+	return nil
+}
+
+func (application *App) newViewData() viewData {
+	return viewData{
+		ReadOnly:        application.config.ReadOnly,
+		CompletedInline: application.config.CompletedInline,
+		Dev:             application.config.Dev,
+		Features:        application.config.Enabled,
+	}
+}
+
+// recordWrite notes the time of a successful write, which dev mode shows in
+// rendered task lists. A failed write may have left the cached counts and
+// streak wrong, so they are recomputed on next use. The caller must hold
+// the mutex.
+func (application *App) recordWrite(err error) {
+	if err == nil {
+		application.lastModified = time.Now()
+	} else {
+		application.counts.stale = true
+		application.streak.valid = false
+	}
+}
+
+// mutating rejects requests to handlers that modify tasks while the app is
+// running in read-only mode or a backup is in progress.
+func (application *App) mutating(handler http.HandlerFunc) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		if application.config.ReadOnly {
+			http.Error(response, "Server is in read-only mode", http.StatusForbidden)
+			return
+		}
+		if application.inMaintenance() {
+			response.Header().Set("Retry-After", backupRetryAfter)
+			http.Error(response, "A backup is in progress, try again shortly", http.StatusServiceUnavailable)
+			return
+		}
+		handler(response, request)
+	}
+}
+
 func (application *App) handleIndex(responseWriter http.ResponseWriter, request *http.Request) {
 	if request.URL.Path != "/" {
-		http.NotFound(responseWriter, request)
+		application.notFound(responseWriter, request)
+		return
+	}
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(responseWriter, "Invalid list id", http.StatusBadRequest)
 		return
 	}
-	err := application.templates.ExecuteTemplate(responseWriter, "index", nil)
+
+	data := application.newViewData()
+	data.ListID = listID
+	data.PollInterval = application.config.PollInterval
+	application.mu.Lock()
+	counts, err := application.currentCounts()
+	data.PendingCount = counts.Pending
+	if err == nil {
+		data.Streak, err = application.currentStreak(time.Now())
+	}
+	if err == nil {
+		data.Lists, err = application.queryLists()
+	}
+	data.GeneratedAt = time.Now()
+	data.LastModified = application.lastModified
+	application.mu.Unlock()
 	if err != nil {
-		http.Error(responseWriter, err.Error(), http.StatusInternalServerError)
+		dbError(responseWriter, "Error loading page", err)
+		return
 	}
+
+	// The page embeds per-request state, so browsers must always revalidate
+	responseWriter.Header().Set("Cache-Control", "no-cache")
+	application.render(responseWriter, "index", data)
 }
 
 func (application *App) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		application.methodNotAllowed(w, r)
 		return
 	}
 
@@ -175,24 +651,55 @@ func (application *App) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	taskID := r.FormValue("taskId")
+	taskID, err := application.ids.decode(r.FormValue("taskId"))
+	if err != nil {
+		http.Error(w, "Invalid task id", http.StatusBadRequest)
+		return
+	}
 	showCompleted := r.FormValue("showCompleted") == "true"
+	listID, err := parseListID(r.FormValue("listId"))
+	if err != nil {
+		http.Error(w, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	if err := application.deleteTask(taskID); err != nil {
+		dbError(w, "Error deleting task", err)
+		return
+	}
+
+	application.renderTasks(w, listID, showCompleted)
+}
 
+// deleteTask removes a task and runs the OnDelete hooks. Deleting a task
+// that is already gone is not an error.
+func (application *App) deleteTask(taskID int64) error {
 	application.mu.Lock()
-	_, err = application.db.Exec("DELETE FROM tasks WHERE id = ?", taskID)
+	deleted, err := scanTask(application.queryRow("DELETE FROM tasks WHERE id = ? RETURNING "+taskColumns, taskID))
+	found := err == nil
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	if found && deleted.Completed {
+		application.adjustCounts(0, -1)
+	} else if found {
+		application.adjustCounts(-1, 0)
+	}
+	application.recordWrite(err)
 	application.mu.Unlock()
 
 	if err != nil {
-		http.Error(w, "Error deleting task: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
-
-	application.renderTasks(w, showCompleted)
+	if found {
+		runHooks("delete", application.hooks.OnDelete, deleted)
+	}
+	return nil
 }
 
 func (application *App) EditTask(responseWriter http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodPost {
-		http.Error(responseWriter, "Invalid request method", http.StatusMethodNotAllowed)
+		application.methodNotAllowed(responseWriter, request)
 		return
 	}
 
@@ -202,56 +709,245 @@ func (application *App) EditTask(responseWriter http.ResponseWriter, request *ht
 		return
 	}
 
-	taskID := request.FormValue("taskId")
-	newTask := request.FormValue("newTask")
+	taskID, err := application.ids.decode(request.FormValue("taskId"))
+	if err != nil {
+		http.Error(responseWriter, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	newTask := application.normalize(request.FormValue("newTask"))
 	showCompleted := request.FormValue("showCompleted") == "true"
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(responseWriter, "Invalid list id", http.StatusBadRequest)
+		return
+	}
 
+	if newTask == "" && application.config.EmptyEditDeletes {
+		if err := application.deleteTask(taskID); err != nil {
+			dbError(responseWriter, "Error deleting task", err)
+			return
+		}
+		application.renderTasks(responseWriter, listID, showCompleted)
+		return
+	}
 	if newTask == "" {
 		http.Error(responseWriter, "Task cannot be empty", http.StatusBadRequest)
 		return
 	}
+	if application.containsBlockedWord(newTask) {
+		http.Error(responseWriter, "Task contains a blocked word", http.StatusBadRequest)
+		return
+	}
+
+	estimate, err := parseMinutes(request.FormValue("estimate"))
+	if err != nil {
+		http.Error(responseWriter, "Invalid estimate: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	application.mu.Lock()
-	_, err = application.db.Exec("UPDATE tasks SET task = ? WHERE id = ?", newTask, taskID)
+	// The estimate is only changed when the form carries it
+	previous, found, err := application.editTask(taskID, newTask, estimate, request.Form.Has("estimate"))
+	application.recordWrite(err)
 	application.mu.Unlock()
 
 	if err != nil {
-		http.Error(responseWriter, "Error updating task: "+err.Error(), http.StatusInternalServerError)
+		dbError(responseWriter, "Error updating task", err)
 		return
 	}
 
-	application.renderTasks(responseWriter, showCompleted)
+	if found {
+		// Lets a client notice that someone else edited the task since it
+		// was last rendered
+		responseWriter.Header().Set("X-Previous-Task", url.PathEscape(previous.Task))
+	}
+	application.renderTasks(responseWriter, listID, showCompleted)
 }
 
-func main() {
-	application := &App{}
+// editTask updates a task's text, and its estimate when setEstimate is set,
+// returning the task as it was before. found is false when there is no
+// such task. The read and the update share a transaction and SQLite allows
+// a single writer at a time, so no other edit can land between them. The
+// caller must hold the mutex.
+func (application *App) editTask(taskID int64, text string, estimate int, setEstimate bool) (previous Task, found bool, err error) {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return Task{}, false, err
+	}
+	defer tx.Rollback()
 
-	tmpl, err := template.ParseFS(assets,
-		"frontend/base.html",
-		"frontend/index.html",
-		"frontend/taskList.html")
+	previous, err = scanTask(tx.QueryRow("SELECT "+taskColumns+" FROM tasks WHERE id = ?", taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, false, nil
+	}
 	if err != nil {
-		log.Fatal("Error parsing templates:", err)
+		return Task{}, false, err
 	}
-	application.templates = tmpl
 
-	err = application.initializeDB()
+	if setEstimate {
+		_, err = tx.Exec("UPDATE tasks SET task = ?, estimate_minutes = ? WHERE id = ?", text, estimate, taskID)
+	} else {
+		_, err = tx.Exec("UPDATE tasks SET task = ? WHERE id = ?", text, taskID)
+	}
 	if err != nil {
-		log.Println("Error initializing database:", err.Error())
+		return Task{}, false, err
+	}
+	return previous, true, tx.Commit()
+}
+
+// templateFiles are the embedded templates, parsed in this order.
+var templateFiles = []string{
+	"frontend/base.html",
+	"frontend/index.html",
+	"frontend/taskList.html",
+	"frontend/errorPage.html",
+	"frontend/print.html",
+	"frontend/lists.html",
+}
+
+// templateFuncs are the functions available to every template.
+func (application *App) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"taskRef":   application.ids.encode,
+		"localTime": application.localTime,
+		"highlight": highlight,
+	}
+}
+
+// parseTemplates parses each template file separately so that a syntax
+// error names the file it came from.
+func parseTemplates(fsys fs.FS, funcs template.FuncMap) (*template.Template, error) {
+	tmpl := template.New("").Funcs(funcs)
+	for _, name := range templateFiles {
+		if _, err := tmpl.ParseFS(fsys, name); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// newHandler wraps mux in the middleware every request goes through. It is
+// built innermost first, so each wrapper runs before the ones above it.
+func newHandler(cfg Config, mux http.Handler) http.Handler {
+	handler := requestTimeout(mux, cfg.RequestTimeout)
+	handler = cors(handler, cfg.CORSOrigin, cfg.CORSMaxAge)
+	handler = canonicalPath(handler)
+	handler = compress(handler, cfg.GzipLevel)
+	handler = limitConcurrency(handler, cfg.MaxConcurrent)
+	handler = accessLog(handler, cfg.Proxies)
+	if cfg.Chaos {
+		slog.Warn("Chaos mode is on: requests are delayed and some fail on purpose",
+			"maxDelay", cfg.ChaosDelay, "errorRate", cfg.ChaosErrorRate)
+		handler = chaos(handler, cfg.ChaosDelay, cfg.ChaosErrorRate)
+	}
+	if cfg.H2C {
+		// Cleartext HTTP/2 for proxies that terminate TLS in front of us
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	return handler
+}
+
+// newHTTPServer returns the server for handler, configured from cfg.
+func newHTTPServer(cfg Config, handler http.Handler) *http.Server {
+	// TLSNextProto is left nil so that ListenAndServeTLS negotiates HTTP/2
+	return &http.Server{
+		Addr:    cfg.Addr,
+		Handler: handler,
+		// Cut off clients that trickle their headers in to hold connections
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+func main() {
+	cfg, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
 		return
 	}
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.Level})))
+
+	application, err := NewApp(cfg)
+	if err != nil {
+		fatal("Error starting app", "error", err)
+	}
+	defer application.db.Close()
+
+	application.routes(http.DefaultServeMux)
+	slog.Info("Features enabled", "features", strings.Join(cfg.Enabled.names(), ","))
 
-	http.HandleFunc("/", application.handleIndex) // This must come first
-	http.HandleFunc("/addTask", application.AddTask)
-	http.HandleFunc("/getTasks", application.GetTasks)
-	http.HandleFunc("/getCompletedTasks", application.GetCompletedTasks)
-	http.HandleFunc("/completeTask", application.CompleteTask)
-	http.HandleFunc("/deleteTask", application.DeleteTask)
-	http.HandleFunc("/editTask", application.EditTask)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	log.Println("Starting HTTP server on http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Println("Error starting HTTP server:", err.Error())
+	var workers sync.WaitGroup
+	if cfg.AutoExportDir != "" {
+		if err := os.MkdirAll(cfg.AutoExportDir, 0o755); err != nil {
+			fatal("Error creating export directory", "error", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			application.autoExport(ctx, cfg.AutoExportDir, cfg.AutoExportInterval, cfg.AutoExportKeep)
+		}()
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		application.refreshCountsPeriodically(ctx)
+	}()
+
+	if cfg.digestEnabled() {
+		mailer, err := newDigestMailer(cfg)
+		if err != nil {
+			fatal("Error preparing digest", "error", err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			application.sendDigests(ctx, mailer, cfg.DigestOffset)
+		}()
+	}
+
+	if cfg.CompletedRetention > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			application.enforceRetention(ctx, cfg.CompletedRetention)
+		}()
 	}
-	log.Println("HTTP server stopped")
+
+	server := newHTTPServer(cfg, newHandler(cfg, http.DefaultServeMux))
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Error shutting down HTTP server", "error", err)
+		}
+	}()
+
+	if cfg.TLSCert != "" {
+		slog.Info("Starting HTTPS server", "addr", cfg.Addr)
+		err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	} else {
+		slog.Info("Starting HTTP server", "addr", cfg.Addr)
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Error starting HTTP server", "error", err)
+	}
+
+	// Stop background workers and wait for them to finish
+	stop()
+	workers.Wait()
+	slog.Info("HTTP server stopped")
 }
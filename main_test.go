@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// newTestApp returns an App backed by a fresh database in a temporary
+// directory, configured from args as if they were given on the command line.
+func newTestApp(t *testing.T, args ...string) *App {
+	t.Helper()
+	args = append([]string{"-db", filepath.Join(t.TempDir(), "tasks.db")}, args...)
+	cfg, err := loadConfig(args, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	application, err := NewApp(cfg)
+	if err != nil {
+		t.Fatalf("NewApp: %v", err)
+	}
+	db := application.db
+	t.Cleanup(func() { db.Close() })
+	return application
+}
+
+// serve sends req through the app's routes and returns the recorded response.
+func (application *App) serve(req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	application.routes(mux)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestParseTemplates(t *testing.T) {
+	application := newTestApp(t)
+	tmpl, err := parseTemplates(assets, application.templateFuncs())
+	if err != nil {
+		t.Fatalf("parseTemplates: %v", err)
+	}
+	for _, name := range []string{"index.html", "taskList.html", "errorPage.html", "print.html", "lists.html"} {
+		if tmpl.Lookup(name) == nil {
+			t.Errorf("template %s not defined", name)
+		}
+	}
+}
+
+func TestParseTemplatesNamesBrokenFile(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, name := range templateFiles {
+		fsys[name] = &fstest.MapFile{Data: []byte(`{{define "x"}}{{end}}`)}
+	}
+	fsys["frontend/print.html"] = &fstest.MapFile{Data: []byte(`{{if}}`)}
+
+	application := newTestApp(t)
+	_, err := parseTemplates(fsys, application.templateFuncs())
+	if err == nil {
+		t.Fatal("parseTemplates succeeded on a broken template")
+	}
+	if !strings.Contains(err.Error(), "frontend/print.html") {
+		t.Errorf("error %q does not name the broken file", err)
+	}
+}
+
+// newLegacyDB creates a database in the schema of the first release, with
+// one pending and one completed task, and returns its path.
+func newLegacyDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task TEXT NOT NULL,
+		completed BOOLEAN NOT NULL DEFAULT 0
+	);
+	INSERT INTO tasks (task, completed) VALUES ('pending', 0), ('done long ago', 1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrationLeavesLegacyCompletionUnknown(t *testing.T) {
+	application := newTestApp(t, "-db", newLegacyDB(t))
+
+	var missing int
+	err := application.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE completed = 1 AND completed_at IS NULL").Scan(&missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != 1 {
+		t.Errorf("%d completed tasks without completed_at, want 1", missing)
+	}
+
+	today := application.serve(httptest.NewRequest(http.MethodGet, "/getCompletedToday", nil))
+	if strings.Contains(today.Body.String(), "done long ago") {
+		t.Error("a task completed before the upgrade is listed as done today")
+	}
+
+	stats := application.serve(httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var body struct {
+		Streak int `json:"streak"`
+	}
+	if err := json.Unmarshal(stats.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding /stats: %v: %s", err, stats.Body)
+	}
+	if body.Streak != 0 {
+		t.Errorf("streak %d right after the upgrade, want 0", body.Streak)
+	}
+}
+
+func TestRetentionExpiresLegacyCompletedTasks(t *testing.T) {
+	application := newTestApp(t, "-db", newLegacyDB(t))
+
+	removed, err := application.deleteCompletedBefore(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Errorf("removed %d tasks right after the upgrade, want 0", removed)
+	}
+
+	// The upgrade happened two days ago
+	if _, err := application.db.Exec("UPDATE schema_migrations SET applied_at = datetime('now', '-48 hours') WHERE version = 1"); err != nil {
+		t.Fatal(err)
+	}
+	removed, err = application.deleteCompletedBefore(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d tasks completed before an upgrade two days ago, want 1", removed)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+var errTaskNotFound = errors.New("task not found")
+
+// MergeTasks folds the source task into the target and deletes the source.
+// The target keeps its id and takes over the source's dependencies, and the
+// source text is appended to the target's unless they only differ in case;
+// tracked time is added together. Both tasks describe the same work, so the
+// target keeps the larger of the two estimates rather than their sum.
+func (application *App) MergeTasks(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sourceID, err := application.ids.decode(request.FormValue("sourceId"))
+	if err != nil {
+		http.Error(response, "Invalid source task id", http.StatusBadRequest)
+		return
+	}
+	targetID, err := application.ids.decode(request.FormValue("targetId"))
+	if err != nil {
+		http.Error(response, "Invalid target task id", http.StatusBadRequest)
+		return
+	}
+	if sourceID == targetID {
+		http.Error(response, "Cannot merge a task into itself", http.StatusBadRequest)
+		return
+	}
+	showCompleted := request.FormValue("showCompleted") == "true"
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	source, err := application.mergeTasks(sourceID, targetID)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if errors.Is(err, errTaskNotFound) {
+		http.Error(response, "Task not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errDependencyCycle) {
+		http.Error(response, "Merge would create a dependency cycle", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error merging tasks", err)
+		return
+	}
+	runHooks("delete", application.hooks.OnDelete, source)
+
+	application.renderTasks(response, listID, showCompleted)
+}
+
+// mergeTasks runs the merge in one transaction and returns the deleted
+// source task. The caller must hold the mutex.
+func (application *App) mergeTasks(sourceID, targetID int64) (Task, error) {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return Task{}, err
+	}
+	defer tx.Rollback()
+
+	var sourceText, targetText string
+	var estimate, actual int
+	err = tx.QueryRow("SELECT task, estimate_minutes, actual_minutes FROM tasks WHERE id = ?", sourceID).Scan(&sourceText, &estimate, &actual)
+	if err == nil {
+		err = tx.QueryRow("SELECT task FROM tasks WHERE id = ?", targetID).Scan(&targetText)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Task{}, errTaskNotFound
+	}
+	if err != nil {
+		return Task{}, err
+	}
+
+	result, err := tx.Exec(`UPDATE tasks
+		SET task = ?, estimate_minutes = max(estimate_minutes, ?), actual_minutes = actual_minutes + ?
+		WHERE id = ?`, mergedText(targetText, sourceText), estimate, actual, targetID)
+	if err != nil {
+		return Task{}, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return Task{}, err
+	} else if updated == 0 {
+		return Task{}, errTaskNotFound
+	}
+
+	// Deleting the source drops its dependencies, so they are copied to the
+	// target first. A dependency between the two tasks would become a
+	// self-dependency and is dropped instead.
+	_, err = tx.Exec(`INSERT OR IGNORE INTO task_dependencies (task_id, depends_on_id)
+		SELECT CASE task_id WHEN ?1 THEN ?2 ELSE task_id END,
+			CASE depends_on_id WHEN ?1 THEN ?2 ELSE depends_on_id END
+		FROM task_dependencies
+		WHERE (task_id = ?1 OR depends_on_id = ?1) AND task_id != ?2 AND depends_on_id != ?2`, sourceID, targetID)
+	if err != nil {
+		return Task{}, err
+	}
+
+	source, err := scanTask(tx.QueryRow("DELETE FROM tasks WHERE id = ? RETURNING "+taskColumns, sourceID))
+	if err != nil {
+		return Task{}, err
+	}
+
+	var cycle int
+	err = tx.QueryRow(`WITH RECURSIVE reachable (id) AS (
+			SELECT depends_on_id FROM task_dependencies WHERE task_id = ?1
+			UNION
+			SELECT task_dependencies.depends_on_id FROM task_dependencies
+			JOIN reachable ON task_dependencies.task_id = reachable.id
+		)
+		SELECT 1 FROM reachable WHERE id = ?1`, targetID).Scan(&cycle)
+	if err == nil {
+		return Task{}, errDependencyCycle
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Task{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Task{}, err
+	}
+
+	if source.Completed {
+		application.adjustCounts(0, -1)
+	} else {
+		application.adjustCounts(-1, 0)
+	}
+	return source, nil
+}
+
+// mergedText is the text of a task that source was merged into.
+func mergedText(target, source string) string {
+	if foldCase(target) == foldCase(source) {
+		return target
+	}
+	return target + "; " + source
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestMergeTasksKeepsLargerEstimate(t *testing.T) {
+	application := newTestApp(t)
+	source, err := application.createTask("write report", 30, defaultListID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := application.createTask("report", 45, defaultListID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := application.db.Exec("UPDATE tasks SET actual_minutes = ? WHERE id = ?", 10, source.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := application.db.Exec("UPDATE tasks SET actual_minutes = ? WHERE id = ?", 5, target.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := application.serve(postForm("/mergeTasks", mergeForm(source, target)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	var estimate, actual int
+	err = application.db.QueryRow("SELECT estimate_minutes, actual_minutes FROM tasks WHERE id = ?", target.ID).Scan(&estimate, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate != 45 {
+		t.Errorf("estimate_minutes = %d, want 45", estimate)
+	}
+	if actual != 15 {
+		t.Errorf("actual_minutes = %d, want 15", actual)
+	}
+}
+
+// mergeForm is the form for merging source into target.
+func mergeForm(source, target Task) url.Values {
+	return url.Values{
+		"sourceId": {strconv.FormatInt(source.ID, 10)},
+		"targetId": {strconv.FormatInt(target.ID, 10)},
+	}
+}
+
+func dependOn(t *testing.T, application *App, task, dependsOn Task) {
+	t.Helper()
+	if err := application.addDependency(task.ID, dependsOn.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeTasksKeepsDependencies(t *testing.T) {
+	application := newTestApp(t)
+	target, _ := application.createTask("target", 0, defaultListID)
+	source, _ := application.createTask("source", 0, defaultListID)
+	dependent, _ := application.createTask("dependent", 0, defaultListID)
+	prerequisite, _ := application.createTask("prerequisite", 0, defaultListID)
+	dependOn(t, application, dependent, source)
+	dependOn(t, application, source, prerequisite)
+	dependOn(t, application, target, prerequisite)
+	dependOn(t, application, source, target)
+
+	recorder := application.serve(postForm("/mergeTasks", mergeForm(source, target)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("merge: status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+
+	rows, err := application.db.Query("SELECT task_id, depends_on_id FROM task_dependencies ORDER BY task_id, depends_on_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var edges [][2]int64
+	for rows.Next() {
+		var edge [2]int64
+		rows.Scan(&edge[0], &edge[1])
+		edges = append(edges, edge)
+	}
+	want := [][2]int64{{target.ID, prerequisite.ID}, {dependent.ID, target.ID}}
+	if !slices.Equal(edges, want) {
+		t.Errorf("dependencies %v, want %v", edges, want)
+	}
+
+	recorder = application.serve(postForm("/completeTask", url.Values{
+		"taskId":    {strconv.FormatInt(dependent.ID, 10)},
+		"completed": {"true"},
+	}))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("completing a task blocked by the merged target: status %d, want 409", recorder.Code)
+	}
+}
+
+func TestMergeTasksRejectsDependencyCycle(t *testing.T) {
+	application := newTestApp(t)
+	target, _ := application.createTask("target", 0, defaultListID)
+	source, _ := application.createTask("source", 0, defaultListID)
+	middle, _ := application.createTask("middle", 0, defaultListID)
+	dependOn(t, application, middle, source)
+	dependOn(t, application, target, middle)
+
+	recorder := application.serve(postForm("/mergeTasks", mergeForm(source, target)))
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("merge: status %d, want 409: %s", recorder.Code, recorder.Body)
+	}
+	if _, err := application.querySingleTask(source.ID); err != nil {
+		t.Errorf("source task gone after a rejected merge: %v", err)
+	}
+}
+
+func TestMergeTasksKeepsSourceText(t *testing.T) {
+	tests := []struct {
+		target, source, want string
+	}{
+		{"report", "write report", "report; write report"},
+		{"Straße fix", "STRASSE FIX", "Straße fix"},
+	}
+	for _, test := range tests {
+		application := newTestApp(t)
+		target, _ := application.createTask(test.target, 0, defaultListID)
+		source, _ := application.createTask(test.source, 0, defaultListID)
+
+		recorder := application.serve(postForm("/mergeTasks", mergeForm(source, target)))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+		}
+		merged, err := application.querySingleTask(target.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged.Task != test.want {
+			t.Errorf("merging %q into %q gave %q, want %q", test.source, test.target, merged.Task, test.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/subtle"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// allowMethods rejects requests whose method is not one of methods with a
+// 405 and an Allow header listing the accepted methods. HEAD is accepted
+// wherever GET is.
+func (application *App) allowMethods(handler http.HandlerFunc, methods ...string) http.HandlerFunc {
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(slices.Clip(methods), http.MethodHead)
+	}
+	allow := strings.Join(methods, ", ")
+	return func(response http.ResponseWriter, request *http.Request) {
+		for _, method := range methods {
+			if request.Method == method {
+				handler(response, request)
+				return
+			}
+		}
+		response.Header().Set("Allow", allow)
+		application.methodNotAllowed(response, request)
+	}
+}
+
+// byMethod dispatches to the handler registered for the request method and
+// rejects any other method like allowMethods. HEAD is served by the GET
+// handler unless it has its own.
+func (application *App) byMethod(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	if get, ok := handlers[http.MethodGet]; ok {
+		if _, ok := handlers[http.MethodHead]; !ok {
+			handlers[http.MethodHead] = get
+		}
+	}
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	allow := strings.Join(methods, ", ")
+
+	return func(response http.ResponseWriter, request *http.Request) {
+		if handler, ok := handlers[request.Method]; ok {
+			handler(response, request)
+			return
+		}
+		response.Header().Set("Allow", allow)
+		application.methodNotAllowed(response, request)
+	}
+}
+
+// requestTimeout replies with a 503 to requests whose handler runs longer
+// than timeout. The handler's context is cancelled at the deadline. A zero
+// timeout disables the limit.
+func requestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout == 0 {
+		return next
+	}
+	return http.TimeoutHandler(next, timeout, "Request timed out, try again shortly")
+}
+
+// chaos delays every request by a random duration up to maxDelay and fails
+// a fraction errorRate of them with a 500, so clients can exercise their
+// loading and error states.
+func chaos(next http.Handler, maxDelay time.Duration, errorRate float64) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if maxDelay > 0 {
+			time.Sleep(time.Duration(rand.Int63n(int64(maxDelay))))
+		}
+		if rand.Float64() < errorRate {
+			http.Error(response, "Chaos mode: injected failure", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(response, request)
+	})
+}
+
+// overloadRetryAfter is the Retry-After hint, in seconds, sent with 503s
+// from limitConcurrency.
+const overloadRetryAfter = "1"
+
+// limitConcurrency rejects requests with a 503 while limit others are
+// being handled. A zero limit disables the check.
+func limitConcurrency(next http.Handler, limit int) http.Handler {
+	if limit == 0 {
+		return next
+	}
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(response, request)
+		default:
+			response.Header().Set("Retry-After", overloadRetryAfter)
+			http.Error(response, "Server is busy, try again shortly", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// canonicalPath redirects requests with a trailing slash to the same path
+// without it, so /getTasks/ and /getTasks resolve to the same route. A 308
+// is used so that the method and body of POST requests are preserved.
+func canonicalPath(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		path := request.URL.Path
+		if path != "/" && strings.HasSuffix(path, "/") {
+			target := *request.URL
+			target.Path = strings.TrimRight(path, "/")
+			if target.Path == "" {
+				target.Path = "/"
+			}
+			http.Redirect(response, request, target.String(), http.StatusPermanentRedirect)
+			return
+		}
+		next.ServeHTTP(response, request)
+	})
+}
+
+// cors adds CORS headers to /api/ responses for the allowed origin and
+// answers preflight requests directly, letting browsers cache the preflight
+// result for maxAge seconds. An empty origin disables CORS.
+func cors(next http.Handler, origin string, maxAge int) http.Handler {
+	if origin == "" {
+		return next
+	}
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		if !strings.HasPrefix(request.URL.Path, "/api/") {
+			next.ServeHTTP(response, request)
+			return
+		}
+
+		header := response.Header()
+		header.Set("Access-Control-Allow-Origin", origin)
+		header.Add("Vary", "Origin")
+
+		if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE")
+			header.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			header.Set("Access-Control-Max-Age", strconv.Itoa(maxAge))
+			response.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(response, request)
+	})
+}
+
+// requireAdmin guards operational endpoints with a bearer token. When no
+// token is configured the endpoints are disabled entirely.
+func requireAdmin(handler http.HandlerFunc, token string) http.HandlerFunc {
+	return func(response http.ResponseWriter, request *http.Request) {
+		if token == "" {
+			http.NotFound(response, request)
+			return
+		}
+		provided, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			response.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(response, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(response, request)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer rewrites task text before it is stored.
+type Normalizer func(string) string
+
+// collapseWhitespace trims the text and replaces every internal run of
+// whitespace with a single space.
+func collapseWhitespace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// normalizeNFC converts the text to Unicode normalization form C so that
+// visually identical tasks are stored identically.
+func normalizeNFC(text string) string {
+	return norm.NFC.String(text)
+}
+
+// foldCase returns text in a form where strings that differ only in case,
+// or in how accented letters are composed, compare equal.
+func foldCase(text string) string {
+	// A Caser keeps state, so each call gets its own
+	return norm.NFC.String(cases.Fold().String(text))
+}
+
+// chainNormalizers returns a Normalizer applying each normalizer in order.
+func chainNormalizers(normalizers ...Normalizer) Normalizer {
+	return func(text string) string {
+		for _, normalize := range normalizers {
+			text = normalize(text)
+		}
+		return text
+	}
+}
+
+func (application *App) normalize(text string) string {
+	if application.normalizer == nil {
+		return text
+	}
+	return application.normalizer(text)
+}
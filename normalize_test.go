@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestFoldCase(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"café", "CAFÉ"},
+		{"Straße", "strasse"},
+		{"STRASSE", "straße"},
+		{"ПРИВЕТ мир", "привет МИР"},
+		// e followed by a combining acute accent composes to é
+		{"cafe\u0301", "café"},
+	}
+	for _, test := range tests {
+		if foldCase(test.a) != foldCase(test.b) {
+			t.Errorf("foldCase(%q) = %q, foldCase(%q) = %q, want equal", test.a, foldCase(test.a), test.b, foldCase(test.b))
+		}
+	}
+
+	if foldCase("café") == foldCase("cafe") {
+		t.Error("foldCase dropped the accent of café")
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"CAFÉ", "%café%"},
+		{"Straße", "%strasse%"},
+		{"ПРИВЕТ", "%привет%"},
+		{"100%_done", `%100\%\_done%`},
+		{`C:\Temp`, `%c:\\temp%`},
+	}
+	for _, test := range tests {
+		if got := likePattern(test.query); got != test.want {
+			t.Errorf("likePattern(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// TaskPreset is a saved task definition that can be added again on demand.
+type TaskPreset struct {
+	ID              int64  `json:"id"`
+	Task            string `json:"task"`
+	EstimateMinutes int    `json:"estimateMinutes"`
+}
+
+// SavePreset stores the text and estimate of taskId as a new preset and
+// returns it.
+func (application *App) SavePreset(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := application.ids.decode(request.FormValue("taskId"))
+	if err != nil {
+		http.Error(response, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	var preset TaskPreset
+	err = application.queryRow(`INSERT INTO task_presets (task, estimate_minutes)
+		SELECT task, estimate_minutes FROM tasks WHERE id = ?
+		RETURNING id, task, estimate_minutes`, taskID).Scan(&preset.ID, &preset.Task, &preset.EstimateMinutes)
+	if !errors.Is(err, sql.ErrNoRows) {
+		application.recordWrite(err)
+	}
+	application.mu.Unlock()
+
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(response, "Task not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error saving preset", err)
+		return
+	}
+	writeJSON(response, http.StatusCreated, preset)
+}
+
+// ApplyPreset adds a new pending task to the listId list from the fields
+// of presetId and renders the list like AddTask.
+func (application *App) ApplyPreset(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	presetID, err := strconv.ParseInt(request.FormValue("presetId"), 10, 64)
+	if err != nil {
+		http.Error(response, "Invalid preset id", http.StatusBadRequest)
+		return
+	}
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	var preset TaskPreset
+	err = application.queryRow("SELECT id, task, estimate_minutes FROM task_presets WHERE id = ?", presetID).
+		Scan(&preset.ID, &preset.Task, &preset.EstimateMinutes)
+	application.mu.Unlock()
+
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(response, "Preset not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error fetching preset", err)
+		return
+	}
+
+	created, err := application.createTask(preset.Task, preset.EstimateMinutes, listID)
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error adding task", err)
+		return
+	}
+
+	response.Header().Set("X-Created-Task-ID", application.ids.encode(created.ID))
+	application.renderTasks(response, listID, false)
+}
@@ -0,0 +1,40 @@
+package main
+
+import "database/sql"
+
+// taskColumns is the column list selected by every task query, in the order
+// scanTask reads them. Columns are qualified so the list also works in
+// queries that join other tables.
+const taskColumns = "tasks.id, tasks.task, tasks.completed, tasks.estimate_minutes, tasks.actual_minutes, tasks.list_id, tasks.completed_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask reads one row selected with taskColumns.
+func scanTask(row rowScanner) (Task, error) {
+	var task Task
+	err := row.Scan(&task.ID, &task.Task, &task.Completed, &task.EstimateMinutes, &task.ActualMinutes, &task.ListID, &task.CompletedAt)
+	// SQLite stores timestamps without a zone; they are always UTC
+	task.CompletedAt.Time = task.CompletedAt.Time.UTC()
+	return task, err
+}
+
+// scanTasks reads every row selected with taskColumns. It never returns a
+// nil slice so that empty results encode as [] in JSON, and it reports an
+// error that ended the iteration early rather than a truncated result.
+func scanTasks(rows *sql.Rows) ([]Task, error) {
+	tasks := []Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
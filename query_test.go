@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// failingDriver adds failAt(id, bad), which errors for the row whose id is
+// bad, so a query can fail partway through its rows.
+const failingDriver = "sqlite3_failing"
+
+func init() {
+	sql.Register(failingDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("failAt", func(id, bad int64) (bool, error) {
+				if id == bad {
+					return false, errors.New("simulated read failure")
+				}
+				return true, nil
+			}, true)
+		},
+	})
+}
+
+// failingQuery selects every task in id order and fails on the row of
+// task bad.
+const failingQuery = "SELECT " + taskColumns + " FROM tasks WHERE failAt(id, ?) ORDER BY id"
+
+func openFailingDB(t *testing.T, application *App) *sql.DB {
+	t.Helper()
+	db, err := sql.Open(failingDriver, application.config.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestScanTasksReportsIterationError(t *testing.T) {
+	application := newTestApp(t)
+	application.createTask("first", 0, defaultListID)
+	second, _ := application.createTask("second", 0, defaultListID)
+	application.createTask("third", 0, defaultListID)
+
+	rows, err := openFailingDB(t, application).Query(failingQuery, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err == nil {
+		t.Fatalf("scanTasks returned %d tasks and no error", len(tasks))
+	}
+	if tasks != nil {
+		t.Errorf("scanTasks returned a partial result of %d tasks", len(tasks))
+	}
+}
+
+func TestRenderTaskQueryFailsOnIterationError(t *testing.T) {
+	application := newTestApp(t)
+	application.createTask("first", 0, defaultListID)
+	second, _ := application.createTask("second", 0, defaultListID)
+	application.db = openFailingDB(t, application)
+
+	recorder := httptest.NewRecorder()
+	application.renderTaskQuery(recorder, failingQuery, second.ID)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500: %s", recorder.Code, recorder.Body)
+	}
+}
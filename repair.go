@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// taskJoinTables lists the tables whose rows reference tasks, with the
+// referencing columns. Triggers clean them up when tasks are deleted, but
+// rows can still be orphaned by writes made outside the app.
+var taskJoinTables = []struct {
+	table   string
+	columns []string
+}{
+	{"task_dependencies", []string{"task_id", "depends_on_id"}},
+}
+
+// RepairReport is the body of GET /admin/repair.
+type RepairReport struct {
+	// Orphans is the number of rows per table that reference a missing
+	// task.
+	Orphans map[string]int64 `json:"orphans"`
+	// Fixed means the orphaned rows were deleted.
+	Fixed bool `json:"fixed"`
+}
+
+// orphanPredicate selects rows of a join table referencing a missing task.
+func orphanPredicate(columns []string) string {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " NOT IN (SELECT id FROM tasks)"
+	}
+	return strings.Join(conditions, " OR ")
+}
+
+// RepairOrphans counts rows in the task join tables that reference tasks
+// which no longer exist. With fix=true it also deletes them, in one
+// transaction; otherwise it only reads.
+func (application *App) RepairOrphans(response http.ResponseWriter, request *http.Request) {
+	if request.URL.Query().Get("fix") != "true" {
+		application.repairOrphans(response, false)
+		return
+	}
+	// Deleting is a write, refused in read-only mode and during backups
+	application.mutating(func(response http.ResponseWriter, request *http.Request) {
+		application.repairOrphans(response, true)
+	})(response, request)
+}
+
+func (application *App) repairOrphans(response http.ResponseWriter, fix bool) {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	tx, err := application.db.Begin()
+	if err != nil {
+		dbError(response, "Error checking orphaned rows", err)
+		return
+	}
+	defer tx.Rollback()
+
+	report := RepairReport{Orphans: make(map[string]int64), Fixed: fix}
+	for _, join := range taskJoinTables {
+		predicate := orphanPredicate(join.columns)
+		var orphans int64
+		if fix {
+			result, err := tx.Exec("DELETE FROM " + join.table + " WHERE " + predicate)
+			if err == nil {
+				orphans, err = result.RowsAffected()
+			}
+			if err != nil {
+				application.recordWrite(err)
+				dbError(response, "Error deleting orphaned rows", err)
+				return
+			}
+		} else if err := tx.QueryRow("SELECT COUNT(*) FROM " + join.table + " WHERE " + predicate).Scan(&orphans); err != nil {
+			dbError(response, "Error checking orphaned rows", err)
+			return
+		}
+		report.Orphans[join.table] = orphans
+	}
+
+	if fix {
+		err = tx.Commit()
+		application.recordWrite(err)
+		if err != nil {
+			dbError(response, "Error deleting orphaned rows", err)
+			return
+		}
+	}
+	writeJSON(response, http.StatusOK, report)
+}
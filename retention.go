@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const retentionCheckInterval = time.Hour
+
+// enforceRetention deletes tasks that were completed more than retention
+// ago, once at startup and then every retentionCheckInterval until ctx is
+// cancelled.
+func (application *App) enforceRetention(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := application.deleteCompletedBefore(retention)
+		if err != nil {
+			slog.Error("Error cleaning up completed tasks", "error", err)
+		} else {
+			slog.Info("Retention cleanup finished", "removed", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retentionPredicate selects tasks completed before a cutoff given as a
+// SQLite datetime modifier. completed_at is stored by SQLite in UTC, so the
+// cutoff is computed with SQLite's own clock rather than a formatted Go time.
+// Tasks completed before completed_at was added have it NULL; they were
+// completed no later than that migration ran, so its time is used for them.
+const retentionPredicate = `completed = 1 AND COALESCE(completed_at,
+	(SELECT applied_at FROM schema_migrations WHERE version = 1)) < datetime('now', ?)`
+
+func retentionModifier(retention time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(retention.Seconds()))
+}
+
+// deleteCompletedBefore deletes the tasks completed more than retention ago
+// and runs the delete hooks for each of them.
+func (application *App) deleteCompletedBefore(retention time.Duration) (int64, error) {
+	application.mu.Lock()
+	var deleted []Task
+	rows, err := application.query("DELETE FROM tasks WHERE "+retentionPredicate+" RETURNING "+taskColumns, retentionModifier(retention))
+	if err == nil {
+		deleted, err = scanTasks(rows)
+		rows.Close()
+	}
+	application.recordWrite(err)
+	if err == nil {
+		application.adjustCounts(0, -len(deleted))
+	}
+	application.mu.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	for _, task := range deleted {
+		runHooks("delete", application.hooks.OnDelete, task)
+	}
+	return int64(len(deleted)), nil
+}
+
+func (application *App) countCompletedBefore(retention time.Duration) (int64, error) {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	var count int64
+	err := application.queryRow("SELECT COUNT(*) FROM tasks WHERE "+retentionPredicate, retentionModifier(retention)).Scan(&count)
+	return count, err
+}
+
+// RunRetention applies the retention policy immediately. The olderThan
+// parameter overrides -completed-retention. With dryRun=true it only
+// reports how many tasks would be deleted.
+func (application *App) RunRetention(response http.ResponseWriter, request *http.Request) {
+	retention := application.config.CompletedRetention
+	if value := request.URL.Query().Get("olderThan"); value != "" {
+		var err error
+		retention, err = time.ParseDuration(value)
+		if err != nil {
+			http.Error(response, "Invalid olderThan: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if retention <= 0 {
+		http.Error(response, "No retention configured; pass olderThan", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := request.URL.Query().Get("dryRun") == "true"
+
+	var affected int64
+	var err error
+	if dryRun {
+		affected, err = application.countCompletedBefore(retention)
+	} else {
+		affected, err = application.deleteCompletedBefore(retention)
+	}
+	if err != nil {
+		dbError(response, "Error applying retention", err)
+		return
+	}
+
+	writeJSON(response, http.StatusOK, map[string]any{"affected": affected, "dryRun": dryRun})
+}
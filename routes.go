@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Optional features that -features can turn off. Routes of a disabled
+// feature are not registered, so they answer 404.
+const (
+	featureSearch = "search" // /searchTasks and the q filter
+	featureExport = "export" // /export.md, /print and /import.csv
+	featureAdmin  = "admin"  // /admin/
+	featureAPI    = "api"    // /api/v1/ and /txt
+)
+
+var allFeatures = []string{featureSearch, featureExport, featureAdmin, featureAPI}
+
+// featureSet is the set of enabled optional features.
+type featureSet map[string]bool
+
+// parseFeatures reads a comma-separated list of feature names.
+func parseFeatures(value string) (featureSet, error) {
+	features := make(featureSet)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allFeatures, name) {
+			return nil, fmt.Errorf("unknown feature %q, want some of %s", name, strings.Join(allFeatures, ", "))
+		}
+		features[name] = true
+	}
+	return features, nil
+}
+
+// names returns the enabled features in a stable order.
+func (features featureSet) names() []string {
+	names := []string{}
+	for _, name := range allFeatures {
+		if features[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// routes registers every handler on mux, leaving out the HTML pages in
+// -api-only mode and the routes of features disabled with -features.
+func (application *App) routes(mux *http.ServeMux) {
+	features := application.config.Enabled
+
+	if application.config.APIOnly {
+		mux.HandleFunc("/", application.allowMethods(application.APIDescriptor, http.MethodGet))
+	} else {
+		mux.HandleFunc("/", application.handleIndex) // This must come first
+		mux.HandleFunc(staticPrefix, application.allowMethods(application.ServeStatic, http.MethodGet))
+		mux.HandleFunc("/addTask", application.mutating(application.AddTask))
+		mux.HandleFunc("/getTasks", application.allowMethods(application.GetTasks, http.MethodGet))
+		mux.HandleFunc("/getCompletedTasks", application.allowMethods(application.GetCompletedTasks, http.MethodGet))
+		mux.HandleFunc("/getCompletedToday", application.allowMethods(application.GetCompletedToday, http.MethodGet))
+		mux.HandleFunc("/focus", application.allowMethods(application.GetFocus, http.MethodGet))
+		mux.HandleFunc("/completeTask", application.mutating(application.CompleteTask))
+		mux.HandleFunc("/deleteTask", application.mutating(application.DeleteTask))
+		mux.HandleFunc("/editTask", application.mutating(application.EditTask))
+		mux.HandleFunc("/logTime", application.mutating(application.LogTime))
+		mux.HandleFunc("/mergeTasks", application.mutating(application.MergeTasks))
+		mux.HandleFunc("/addDependency", application.mutating(application.AddDependency))
+		mux.HandleFunc("/savePreset", application.mutating(application.SavePreset))
+		mux.HandleFunc("/applyPreset", application.mutating(application.ApplyPreset))
+		mux.HandleFunc("/getLists", application.allowMethods(application.GetLists, http.MethodGet))
+		mux.HandleFunc("/createList", application.mutating(application.CreateList))
+		mux.HandleFunc("/deleteList", application.mutating(application.DeleteList))
+		if features[featureSearch] {
+			mux.HandleFunc("/searchTasks", application.allowMethods(application.SearchTasks, http.MethodGet))
+		}
+		if features[featureExport] {
+			mux.HandleFunc("/print", application.allowMethods(application.PrintTasks, http.MethodGet))
+		}
+	}
+
+	if features[featureAPI] {
+		mux.HandleFunc("/api/v1/tasks", application.byMethod(map[string]http.HandlerFunc{
+			http.MethodGet:    application.APIGetTasks,
+			http.MethodPost:   application.mutating(application.APICreateTask),
+			http.MethodDelete: application.mutating(application.APIDeleteAllTasks),
+		}))
+		mux.HandleFunc("/api/v1/tasks/delete-token", application.allowMethods(application.APIGetDeleteToken, http.MethodGet))
+		mux.HandleFunc("/api/v1/tasks/", application.APIGetTask)
+		mux.HandleFunc("/api/v1/tasks/order", application.mutating(application.allowMethods(application.APIReorderTasks, http.MethodPatch)))
+		mux.HandleFunc("/txt", application.byMethod(map[string]http.HandlerFunc{
+			http.MethodGet:  application.GetTextTasks,
+			http.MethodPost: application.mutating(application.AddTextTask),
+		}))
+	}
+
+	mux.HandleFunc("/stats", application.allowMethods(application.GetStats, http.MethodGet))
+	mux.HandleFunc("/stats/history", application.allowMethods(application.GetStatsHistory, http.MethodGet))
+
+	if features[featureExport] {
+		mux.HandleFunc("/import.csv", application.mutating(application.allowMethods(application.ImportCSV, http.MethodPost)))
+		mux.HandleFunc("/export.md", application.allowMethods(application.ExportMarkdown, http.MethodGet))
+	}
+
+	if features[featureAdmin] {
+		token := application.config.AdminToken
+		mux.HandleFunc("/admin/backup", requireAdmin(application.allowMethods(application.Backup, http.MethodPost), token))
+		mux.HandleFunc("/admin/retention", requireAdmin(application.mutating(application.allowMethods(application.RunRetention, http.MethodPost)), token))
+		mux.HandleFunc("/admin/integrity", requireAdmin(application.allowMethods(application.CheckIntegrity, http.MethodGet), token))
+		mux.HandleFunc("/admin/version", requireAdmin(application.allowMethods(application.GetVersion, http.MethodGet), token))
+		mux.HandleFunc("/admin/repair", requireAdmin(application.allowMethods(application.RepairOrphans, http.MethodGet), token))
+		mux.HandleFunc("/admin/slow", requireAdmin(application.allowMethods(application.GetSlowQueries, http.MethodGet), token))
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchFeatureToggle(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		search   bool
+		apiQuery int
+	}{
+		{"enabled", nil, true, http.StatusOK},
+		{"disabled", []string{"-features", "export,admin,api"}, false, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			application := newTestApp(t, test.args...)
+
+			index := application.serve(httptest.NewRequest(http.MethodGet, "/", nil))
+			if index.Code != http.StatusOK {
+				t.Fatalf("index: status %d, want 200", index.Code)
+			}
+			if got := strings.Contains(index.Body.String(), `hx-get="/searchTasks"`); got != test.search {
+				t.Errorf("index shows the search box = %v, want %v", got, test.search)
+			}
+
+			want := http.StatusNotFound
+			if test.search {
+				want = http.StatusOK
+			}
+			if code := application.serve(httptest.NewRequest(http.MethodGet, "/searchTasks?q=milk", nil)).Code; code != want {
+				t.Errorf("/searchTasks: status %d, want %d", code, want)
+			}
+			if code := application.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks?q=milk", nil)).Code; code != test.apiQuery {
+				t.Errorf("/api/v1/tasks?q=: status %d, want %d", code, test.apiQuery)
+			}
+			if code := application.serve(httptest.NewRequest(http.MethodGet, "/api/v1/tasks", nil)).Code; code != http.StatusOK {
+				t.Errorf("/api/v1/tasks: status %d, want 200", code)
+			}
+		})
+	}
+}
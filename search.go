@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// sqliteDriver is go-sqlite3 with a casefold(text) SQL function, which
+// LIKE searches use for Unicode-aware case-insensitive matching. SQLite's
+// own LIKE only folds ASCII.
+const sqliteDriver = "sqlite3_tasks"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("casefold", foldCase, true)
+		},
+	})
+}
+
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag
+// (go build -tags sqlite_fts5), so setupSearch probes for it and the search
+// falls back to LIKE without it.
+//
+// The index is contentless and holds casefold(task), so FTS matches fold
+// case exactly like the LIKE search: "strasse" finds "Straße". Diacritics
+// are kept for the same reason. The triggers call casefold, so only
+// connections opened with sqliteDriver can write to tasks.
+const ftsSchema = `
+DROP TABLE IF EXISTS tasks_fts;
+CREATE VIRTUAL TABLE tasks_fts USING fts5(task, content='', tokenize='unicode61 remove_diacritics 0');
+CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+	INSERT INTO tasks_fts (rowid, task) VALUES (new.id, casefold(new.task));
+END;
+CREATE TRIGGER tasks_fts_delete AFTER DELETE ON tasks BEGIN
+	INSERT INTO tasks_fts (tasks_fts, rowid, task) VALUES ('delete', old.id, casefold(old.task));
+END;
+CREATE TRIGGER tasks_fts_update AFTER UPDATE OF task ON tasks BEGIN
+	INSERT INTO tasks_fts (tasks_fts, rowid, task) VALUES ('delete', old.id, casefold(old.task));
+	INSERT INTO tasks_fts (rowid, task) VALUES (new.id, casefold(new.task));
+END;
+INSERT INTO tasks_fts (rowid, task) SELECT id, casefold(task) FROM tasks;`
+
+const dropFTSTriggers = `
+DROP TRIGGER IF EXISTS tasks_fts_insert;
+DROP TRIGGER IF EXISTS tasks_fts_delete;
+DROP TRIGGER IF EXISTS tasks_fts_update;`
+
+// setupSearch enables the FTS5 index when the SQLite build supports it. The
+// index is rebuilt whenever its triggers are missing, which covers both a
+// new database and one last opened by a build without FTS5, and when they
+// predate the folded index.
+func (application *App) setupSearch() error {
+	var name string
+	err := application.db.QueryRow("SELECT name FROM pragma_module_list WHERE name = 'fts5'").Scan(&name)
+	if err == sql.ErrNoRows {
+		slog.Info("SQLite was built without FTS5, search will use LIKE; build with -tags sqlite_fts5 to enable it")
+		// Triggers left over from an FTS5 build would make every write fail
+		_, err = application.db.Exec(dropFTSTriggers)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	var trigger string
+	err = application.db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'tasks_fts_insert'").Scan(&trigger)
+	if err == sql.ErrNoRows || (err == nil && !strings.Contains(trigger, "casefold")) {
+		tx, err := application.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(dropFTSTriggers + ftsSchema); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	application.fts = true
+	return nil
+}
+
+// SearchTasks renders the tasks in a list matching the q query parameter,
+// best matches first when the FTS5 index is available.
+func (application *App) SearchTasks(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	query := strings.TrimSpace(request.URL.Query().Get("q"))
+	if query == "" {
+		application.renderTasks(response, listID, false)
+		return
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	data := application.newViewData()
+	data.Highlight = query
+	if application.fts {
+		application.renderTaskQueryData(response, data, `SELECT `+taskColumns+`
+			FROM tasks_fts JOIN tasks ON tasks.id = tasks_fts.rowid
+			WHERE tasks.list_id = ? AND tasks_fts MATCH ? ORDER BY rank`, listID, ftsQuery(foldCase(query)))
+	} else {
+		application.renderTaskQueryData(response, data, `SELECT `+taskColumns+` FROM tasks
+			WHERE list_id = ? AND casefold(task) LIKE ? ESCAPE '\' ORDER BY position, id DESC`, listID, likePattern(query))
+	}
+}
+
+// highlight HTML-escapes text and wraps each occurrence of a word of query
+// in <mark>. Text and query are compared after foldCase, as the search
+// compares them, so "strasse" marks "Straße". Matches are found in the raw
+// text and every piece is escaped on its own, so neither the text nor the
+// query can inject markup or split an entity.
+func highlight(text, query string) template.HTML {
+	// Fold text a normalization segment at a time, remembering which
+	// segment each folded byte came from, so matches in the folded text
+	// map back to whole runes of the original
+	var folded strings.Builder
+	var segments [][2]int
+	for start := 0; start < len(text); {
+		end := start + norm.NFC.NextBoundaryInString(text[start:], true)
+		piece := foldCase(text[start:end])
+		folded.WriteString(piece)
+		for i := 0; i < len(piece); i++ {
+			segments = append(segments, [2]int{start, end})
+		}
+		start = end
+	}
+	haystack := folded.String()
+
+	marked := make([]bool, len(text))
+	for _, word := range strings.Fields(query) {
+		needle := foldCase(word)
+		if needle == "" {
+			continue
+		}
+		for offset := 0; offset < len(haystack); {
+			index := strings.Index(haystack[offset:], needle)
+			if index < 0 {
+				break
+			}
+			first, last := offset+index, offset+index+len(needle)-1
+			for i := segments[first][0]; i < segments[last][1]; i++ {
+				marked[i] = true
+			}
+			_, size := utf8.DecodeRuneInString(haystack[first:])
+			offset = first + size
+		}
+	}
+
+	var out strings.Builder
+	for start := 0; start < len(text); {
+		end := start
+		for end < len(text) && marked[end] == marked[start] {
+			end++
+		}
+		if marked[start] {
+			out.WriteString("<mark>" + template.HTMLEscapeString(text[start:end]) + "</mark>")
+		} else {
+			out.WriteString(template.HTMLEscapeString(text[start:end]))
+		}
+		start = end
+	}
+	return template.HTML(out.String())
+}
+
+// ftsQuery turns free text into an FTS5 query that prefix-matches every
+// word. Each word is quoted so FTS5 operators in user input are literal.
+func ftsQuery(text string) string {
+	words := strings.Fields(text)
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// likePattern builds a case-folded substring LIKE pattern, escaping the
+// LIKE wildcards, for matching against casefold(column).
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(foldCase(text))
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,88 @@
+//go:build sqlite_fts5
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchUsesFTS(t *testing.T) {
+	application := newTestApp(t)
+	if !application.fts {
+		t.Fatal("FTS5 index not enabled despite the sqlite_fts5 tag")
+	}
+
+	for i := 0; i < 500; i++ {
+		if _, err := application.createTask(fmt.Sprintf("filler task %d", i), 0, defaultListID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, text := range []string{"fixing the build", "prefix match"} {
+		if _, err := application.createTask(text, 0, defaultListID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var indexed int
+	if err := application.db.QueryRow("SELECT count(*) FROM tasks_fts WHERE tasks_fts MATCH 'filler'").Scan(&indexed); err != nil {
+		t.Fatal(err)
+	}
+	if indexed != 500 {
+		t.Errorf("tasks_fts has %d filler rows, want 500", indexed)
+	}
+
+	// FTS prefix-matches words while LIKE matches any substring, so only
+	// the FTS index leaves out "prefix match" for the query "fix"
+	recorder := application.serve(httptest.NewRequest(http.MethodGet, "/searchTasks?q=fix", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", recorder.Code)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, "<mark>fix</mark>ing the build") {
+		t.Errorf("body does not contain the FTS match:\n%s", body)
+	}
+	if strings.Contains(body, "prefix match") {
+		t.Errorf("body contains a substring match, so LIKE was used:\n%s", body)
+	}
+}
+
+func TestFTSIndexIsFolded(t *testing.T) {
+	application := newTestApp(t)
+	if _, err := application.createTask("Straße fix", 0, defaultListID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Put back the unfolded index of earlier versions; setupSearch must
+	// replace it
+	_, err := application.db.Exec(dropFTSTriggers + `
+		DROP TABLE tasks_fts;
+		CREATE VIRTUAL TABLE tasks_fts USING fts5(task, content='tasks', content_rowid='id');
+		CREATE TRIGGER tasks_fts_insert AFTER INSERT ON tasks BEGIN
+			INSERT INTO tasks_fts (rowid, task) VALUES (new.id, new.task);
+		END;
+		INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := application.setupSearch(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{"strasse", "STRASSE", "straße"} {
+		var count int
+		err := application.db.QueryRow("SELECT count(*) FROM tasks_fts WHERE tasks_fts MATCH ?", ftsQuery(foldCase(query))).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("FTS match for %q found %d tasks, want 1", query, count)
+		}
+		if body := searchBody(t, application, query); !strings.Contains(body, "fix") {
+			t.Errorf("search %q did not find the task:\n%s", query, body)
+		}
+	}
+}
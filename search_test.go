@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// searchBody runs /searchTasks for query and returns the rendered list.
+func searchBody(t *testing.T, application *App, query string) string {
+	t.Helper()
+	recorder := application.serve(httptest.NewRequest(http.MethodGet, "/searchTasks?q="+url.QueryEscape(query), nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("search %q: status %d, want 200: %s", query, recorder.Code, recorder.Body)
+	}
+	return recorder.Body.String()
+}
+
+// TestSearchFoldsCase holds for both the LIKE and the FTS5 search, so it
+// only uses queries that are whole words or word prefixes.
+func TestSearchFoldsCase(t *testing.T) {
+	application := newTestApp(t)
+	for _, text := range []string{"Café order", "Straße fix", "Привет мир"} {
+		if _, err := application.createTask(text, 0, defaultListID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// marker is a word of the task that the query does not touch, so
+	// highlighting cannot split it
+	tests := []struct {
+		query, marker string
+		found         bool
+	}{
+		{"CAFÉ", "order", true},
+		{"café", "order", true},
+		{"cafe", "order", false},
+		{"strasse", "fix", true},
+		{"STRASSE", "fix", true},
+		{"straße", "fix", true},
+		{"ПРИВЕТ", "мир", true},
+		{"МИР", "Привет", true},
+	}
+	for _, test := range tests {
+		body := searchBody(t, application, test.query)
+		if found := strings.Contains(body, test.marker); found != test.found {
+			t.Errorf("search %q: found the task = %v, want %v", test.query, found, test.found)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text, query string
+		want        string
+	}{
+		{"Buy milk", "milk", "Buy <mark>milk</mark>"},
+		{"Buy MILK", "milk", "Buy <mark>MILK</mark>"},
+		{"Café order", "CAFÉ", "<mark>Café</mark> order"},
+		{"Straße fix", "strasse", "<mark>Straße</mark> fix"},
+		{"STRASSE fix", "straße", "<mark>STRASSE</mark> fix"},
+		{"Привет мир", "МИР", "Привет <mark>мир</mark>"},
+		{"café time", "café", "<mark>café</mark> time"},
+		{"Café order", "cafe", "Café order"},
+		{"a < b && c > d", "<", "a <mark>&lt;</mark> b &amp;&amp; c &gt; d"},
+		{"a < b && c > d", "&&", "a &lt; b <mark>&amp;&amp;</mark> c &gt; d"},
+		{"a < b && c > d", "> d", "a &lt; b &amp;&amp; c <mark>&gt;</mark> <mark>d</mark>"},
+		{"Tom & Jerry", "amp", "Tom &amp; Jerry"},
+		{"<b>bold</b>", "b", "&lt;<mark>b</mark>&gt;<mark>b</mark>old&lt;/<mark>b</mark>&gt;"},
+		{"cafe\u0301 time", "café", "<mark>cafe\u0301</mark> time"},
+		{"nothing here", "", "nothing here"},
+	}
+	for _, test := range tests {
+		if got := string(highlight(test.text, test.query)); got != test.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", test.text, test.query, got, test.want)
+		}
+	}
+}
+
+// TestSearchHighlightsWhatItFinds checks that every task a search returns
+// has its match marked, whichever way the text is folded.
+func TestSearchHighlightsWhatItFinds(t *testing.T) {
+	application := newTestApp(t)
+	if _, err := application.createTask("Straße fix", 0, defaultListID); err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{"strasse", "STRASSE", "straße"} {
+		if body := searchBody(t, application, query); !strings.Contains(body, "<mark>Straße</mark> fix") {
+			t.Errorf("search %q did not mark the match:\n%s", query, body)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestServer returns an unstarted httptest server running the app
+// behind the same middleware and server settings as main.
+func newTestServer(t *testing.T, args ...string) *httptest.Server {
+	t.Helper()
+	application := newTestApp(t, args...)
+	mux := http.NewServeMux()
+	application.routes(mux)
+
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = newHTTPServer(application.config, newHandler(application.config, mux))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerNegotiatesHTTP2OverTLS(t *testing.T) {
+	server := newTestServer(t)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+
+	resp, err := server.Client().Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", resp.StatusCode)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol %s, want HTTP/2", resp.Proto)
+	}
+}
+
+func TestServerRejectsOversizedHeaders(t *testing.T) {
+	server := newTestServer(t, "-max-header-bytes", "1024")
+	server.Start()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// net/http allows 4096 bytes on top of MaxHeaderBytes before it gives up
+	req.Header.Set("X-Padding", strings.Repeat("x", 8<<10))
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("status %d, want 431", resp.StatusCode)
+	}
+
+	req.Header.Del("X-Padding")
+	resp, err = server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d for small headers, want 200", resp.StatusCode)
+	}
+}
+
+// rawRequest sends request over a new connection and returns everything
+// the server wrote back, so bytes a client would discard are still seen.
+func rawRequest(t *testing.T, server *httptest.Server, request string) (header, body string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, _ = strings.Cut(string(raw), "\r\n\r\n")
+	return header, body
+}
+
+func TestHeadSendsNoBody(t *testing.T) {
+	server := newTestServer(t)
+	server.Start()
+	created, err := server.Client().Post(server.URL+"/api/v1/tasks", "application/json", strings.NewReader(`{"task": "Buy milk"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created.Body.Close()
+
+	// / and /getTasks use allowMethods, /api/v1/tasks and /txt use byMethod
+	for _, path := range []string{"/", "/getTasks", "/api/v1/tasks", "/txt"} {
+		t.Run(path, func(t *testing.T) {
+			get, err := server.Client().Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			getBody, _ := io.ReadAll(get.Body)
+			get.Body.Close()
+			if get.StatusCode != http.StatusOK || len(getBody) == 0 {
+				t.Fatalf("GET: status %d with %d bytes, want 200 with a body", get.StatusCode, len(getBody))
+			}
+
+			for _, encoding := range []string{"identity", "gzip"} {
+				header, body := rawRequest(t, server, "HEAD "+path+" HTTP/1.1\r\nHost: tasks\r\n"+
+					"Accept-Encoding: "+encoding+"\r\nConnection: close\r\n\r\n")
+				if !strings.HasPrefix(header, "HTTP/1.1 200") {
+					t.Errorf("HEAD with %s: got %q, want 200", encoding, strings.SplitN(header, "\r\n", 2)[0])
+				}
+				if body != "" {
+					t.Errorf("HEAD with %s: sent a %d byte body", encoding, len(body))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const slowQueryHistory = 50
+
+// SlowQuery is a statement that took longer than the configured threshold.
+type SlowQuery struct {
+	Query      string    `json:"query"`
+	DurationMs float64   `json:"durationMs"`
+	At         time.Time `json:"at"`
+}
+
+// slowQueryLog keeps the most recent slow queries in a ring buffer.
+type slowQueryLog struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	entries   []SlowQuery
+	next      int
+}
+
+func (slow *slowQueryLog) observe(query string, start time.Time) {
+	if slow == nil || slow.threshold <= 0 {
+		return
+	}
+	duration := time.Since(start)
+	if duration < slow.threshold {
+		return
+	}
+
+	query = strings.Join(strings.Fields(query), " ")
+	slog.Warn("Slow query", "query", query, "duration", duration)
+
+	slow.mu.Lock()
+	defer slow.mu.Unlock()
+	entry := SlowQuery{Query: query, DurationMs: float64(duration.Microseconds()) / 1000, At: start}
+	if len(slow.entries) < slowQueryHistory {
+		slow.entries = append(slow.entries, entry)
+	} else {
+		slow.entries[slow.next] = entry
+	}
+	slow.next = (slow.next + 1) % slowQueryHistory
+}
+
+// recent returns the recorded slow queries, newest first.
+func (slow *slowQueryLog) recent() []SlowQuery {
+	slow.mu.Lock()
+	defer slow.mu.Unlock()
+
+	recent := make([]SlowQuery, 0, len(slow.entries))
+	for i := 1; i <= len(slow.entries); i++ {
+		recent = append(recent, slow.entries[(slow.next-i+len(slow.entries))%len(slow.entries)])
+	}
+	return recent
+}
+
+// exec, query and queryRow wrap the database calls made while serving
+// requests so that slow statements are recorded.
+func (application *App) exec(query string, args ...any) (sql.Result, error) {
+	defer application.slowQueries.observe(query, time.Now())
+	return application.db.Exec(query, args...)
+}
+
+func (application *App) query(query string, args ...any) (*sql.Rows, error) {
+	defer application.slowQueries.observe(query, time.Now())
+	return application.db.Query(query, args...)
+}
+
+func (application *App) queryRow(query string, args ...any) *sql.Row {
+	defer application.slowQueries.observe(query, time.Now())
+	return application.db.QueryRow(query, args...)
+}
+
+// GetSlowQueries returns the most recent slow queries as JSON.
+func (application *App) GetSlowQueries(response http.ResponseWriter, request *http.Request) {
+	writeJSON(response, http.StatusOK, application.slowQueries.recent())
+}
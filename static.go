@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const staticPrefix = "/static/"
+
+// staticFile is an embedded asset with the ETag derived from its content.
+type staticFile struct {
+	content []byte
+	etag    string
+}
+
+// loadStaticFiles reads every non-template file in dir of fsys. The
+// templates are rendered by handlers and never served as-is.
+func loadStaticFiles(fsys fs.FS, dir string) (map[string]staticFile, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]staticFile)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) == ".html" || path.Ext(entry.Name()) == ".txt" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		files[entry.Name()] = staticFile{content: content, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+	}
+	return files, nil
+}
+
+// ServeStatic serves the embedded frontend assets under /static/. They may
+// be cached for -static-max-age, after which the ETag lets browsers
+// revalidate cheaply until a deploy changes the content.
+func (application *App) ServeStatic(response http.ResponseWriter, request *http.Request) {
+	name := strings.TrimPrefix(request.URL.Path, staticPrefix)
+	file, ok := application.static[name]
+	if !ok {
+		application.notFound(response, request)
+		return
+	}
+
+	header := response.Header()
+	header.Set("ETag", file.etag)
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(application.config.StaticMaxAge.Seconds())))
+	// ServeContent answers If-None-Match against the ETag with a 304
+	http.ServeContent(response, request, name, time.Time{}, bytes.NewReader(file.content))
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// streakCache holds the last computed completion streak. It is only valid
+// for the day it was computed on, in the configured timezone, and is
+// guarded by App.mu.
+type streakCache struct {
+	days  int
+	day   string
+	valid bool
+}
+
+// Stats is the body of GET /stats.
+type Stats struct {
+	Pending   int `json:"pending"`
+	Completed int `json:"completed"`
+	// Streak is the number of consecutive days, ending today or yesterday,
+	// on which at least one task was completed.
+	Streak int `json:"streak"`
+}
+
+// currentStreak returns the completion streak as of now, recomputing it
+// when the cache is invalid or from an earlier day. The caller must hold
+// the mutex.
+func (application *App) currentStreak(now time.Time) (int, error) {
+	location := application.config.Location
+	today := now.In(location).Format(dateLayout)
+	if application.streak.valid && application.streak.day == today {
+		return application.streak.days, nil
+	}
+
+	rows, err := application.query("SELECT completed_at FROM tasks WHERE completed = 1 AND completed_at IS NOT NULL")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	days := make(map[string]bool)
+	for rows.Next() {
+		var completedAt time.Time
+		if err := rows.Scan(&completedAt); err != nil {
+			return 0, err
+		}
+		days[completedAt.In(location).Format(dateLayout)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	streak := countStreak(days, now.In(location))
+	application.streak = streakCache{days: streak, day: today, valid: true}
+	return streak, nil
+}
+
+// countStreak counts the consecutive days in days ending at today. A streak
+// that ended yesterday still counts, since today may yet get a completion.
+func countStreak(days map[string]bool, today time.Time) int {
+	// Step from noon so that DST changes never skip or repeat a date
+	cursor := time.Date(today.Year(), today.Month(), today.Day(), 12, 0, 0, 0, today.Location())
+	if !days[cursor.Format(dateLayout)] {
+		cursor = cursor.AddDate(0, 0, -1)
+	}
+
+	streak := 0
+	for days[cursor.Format(dateLayout)] {
+		streak++
+		cursor = cursor.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// GetStats returns the task counts and the current completion streak as
+// JSON.
+func (application *App) GetStats(response http.ResponseWriter, request *http.Request) {
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	counts, err := application.currentCounts()
+	if err != nil {
+		dbError(response, "Error counting tasks", err)
+		return
+	}
+	streak, err := application.currentStreak(time.Now())
+	if err != nil {
+		dbError(response, "Error computing streak", err)
+		return
+	}
+	writeJSON(response, http.StatusOK, Stats{Pending: counts.Pending, Completed: counts.Completed, Streak: streak})
+}
+
+// defaultHistoryBuckets is how many buckets of each period GET
+// /stats/history returns when no from date is given.
+var defaultHistoryBuckets = map[string]int{"day": 30, "week": 12, "month": 12}
+
+// maxHistoryBuckets bounds the work a single history request can ask for.
+const maxHistoryBuckets = 400
+
+// HistoryBucket is the number of tasks completed in the period starting on
+// Start, a date in the configured timezone.
+type HistoryBucket struct {
+	Start string `json:"start"`
+	Count int    `json:"count"`
+}
+
+// StatsHistory is the body of GET /stats/history. Buckets are oldest
+// first and include periods with no completions.
+type StatsHistory struct {
+	Period  string          `json:"period"`
+	Buckets []HistoryBucket `json:"buckets"`
+}
+
+// bucketStart returns the start of the day, week (from Monday) or month
+// containing t, in t's location.
+func bucketStart(t time.Time, period string) time.Time {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+	case "month":
+		return start.AddDate(0, 0, 1-start.Day())
+	}
+	return start
+}
+
+func nextBucket(start time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// parseHistoryRange reads period, from and to. from and to are dates in
+// location; to defaults to today and from to a period-dependent window
+// before it.
+func parseHistoryRange(query url.Values, now time.Time) (period string, from, to time.Time, err error) {
+	period = query.Get("period")
+	if period == "" {
+		period = "day"
+	}
+	buckets, ok := defaultHistoryBuckets[period]
+	if !ok {
+		return "", time.Time{}, time.Time{}, errors.New("period must be day, week or month")
+	}
+
+	to = bucketStart(now, period)
+	if value := query.Get("to"); value != "" {
+		date, err := time.ParseInLocation(dateLayout, value, now.Location())
+		if err != nil {
+			return "", time.Time{}, time.Time{}, errors.New("to must be a date like 2006-01-02")
+		}
+		to = bucketStart(date, period)
+	}
+
+	from = to
+	for i := 1; i < buckets; i++ {
+		from = bucketStart(from.AddDate(0, 0, -1), period)
+	}
+	if value := query.Get("from"); value != "" {
+		date, err := time.ParseInLocation(dateLayout, value, now.Location())
+		if err != nil {
+			return "", time.Time{}, time.Time{}, errors.New("from must be a date like 2006-01-02")
+		}
+		from = bucketStart(date, period)
+	}
+	if from.After(to) {
+		return "", time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return period, from, to, nil
+}
+
+// GetStatsHistory returns the number of tasks completed per day, week or
+// month between the from and to dates, for charting. Buckets follow the
+// configured timezone, so completions are grouped here rather than with
+// SQLite's date(), which only knows UTC.
+func (application *App) GetStatsHistory(response http.ResponseWriter, request *http.Request) {
+	period, from, to, err := parseHistoryRange(request.URL.Query(), time.Now().In(application.config.Location))
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history := StatsHistory{Period: period, Buckets: []HistoryBucket{}}
+	index := make(map[string]int)
+	for start := from; !start.After(to); start = nextBucket(start, period) {
+		if len(history.Buckets) == maxHistoryBuckets {
+			http.Error(response, "Range covers too many buckets", http.StatusBadRequest)
+			return
+		}
+		index[start.Format(dateLayout)] = len(history.Buckets)
+		history.Buckets = append(history.Buckets, HistoryBucket{Start: start.Format(dateLayout)})
+	}
+
+	application.mu.Lock()
+	defer application.mu.Unlock()
+
+	rows, err := application.query(`SELECT completed_at FROM tasks
+		WHERE completed = 1 AND completed_at >= ? AND completed_at < ?`,
+		sqliteTime(from), sqliteTime(nextBucket(to, period)))
+	if err != nil {
+		dbError(response, "Error fetching completions", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var completedAt time.Time
+		if err := rows.Scan(&completedAt); err != nil {
+			dbError(response, "Error scanning completion", err)
+			return
+		}
+		start := bucketStart(completedAt.In(application.config.Location), period)
+		if i, ok := index[start.Format(dateLayout)]; ok {
+			history.Buckets[i].Count++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		dbError(response, "Error fetching completions", err)
+		return
+	}
+	writeJSON(response, http.StatusOK, history)
+}
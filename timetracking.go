@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// parseMinutes parses an optional non-negative number of minutes. An empty
+// value means zero.
+func parseMinutes(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 0 {
+		return 0, errors.New("minutes must be a non-negative integer")
+	}
+	return minutes, nil
+}
+
+// LogTime adds the given number of minutes to a task's tracked time.
+func (application *App) LogTime(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		application.methodNotAllowed(response, request)
+		return
+	}
+
+	err := request.ParseForm()
+	if err != nil {
+		http.Error(response, "Error parsing form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taskID, err := application.ids.decode(request.FormValue("taskId"))
+	if err != nil {
+		http.Error(response, "Invalid task id", http.StatusBadRequest)
+		return
+	}
+	showCompleted := request.FormValue("showCompleted") == "true"
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	minutes, err := parseMinutes(request.FormValue("minutes"))
+	if err != nil || minutes == 0 {
+		http.Error(response, "Minutes must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	application.mu.Lock()
+	_, err = application.exec("UPDATE tasks SET actual_minutes = actual_minutes + ? WHERE id = ?", minutes, taskID)
+	application.recordWrite(err)
+	application.mu.Unlock()
+
+	if err != nil {
+		dbError(response, "Error logging time", err)
+		return
+	}
+
+	application.renderTasks(response, listID, showCompleted)
+}
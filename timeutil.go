@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// sqliteTimeLayout matches the text SQLite's CURRENT_TIMESTAMP produces, so
+// formatted values compare correctly against stored timestamps.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// sqliteMillisLayout matches strftime('%Y-%m-%d %H:%M:%f'), used for the
+// millisecond-precision sync timestamps.
+const sqliteMillisLayout = "2006-01-02 15:04:05.000"
+
+// sqliteTime formats t in UTC for comparison with SQLite timestamp columns.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// localTime converts t to the configured timezone for display. Timestamps
+// are stored and scanned in UTC, and only converted when rendered.
+func (application *App) localTime(t time.Time) time.Time {
+	return t.In(application.config.Location)
+}
+
+// dayBounds returns the start of the day containing now in location and the
+// start of the following day.
+func dayBounds(now time.Time, location *time.Location) (start, end time.Time) {
+	local := now.In(location)
+	start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// nullTime is a sql.NullTime that encodes to JSON as an RFC 3339 timestamp,
+// or null when it is not valid.
+type nullTime struct {
+	sql.NullTime
+}
+
+func (t nullTime) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time)
+}
+
+func (t *nullTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = nullTime{}
+		return nil
+	}
+	if err := json.Unmarshal(data, &t.Time); err != nil {
+		return err
+	}
+	t.Valid = true
+	return nil
+}
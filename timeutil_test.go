@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	_ "time/tzdata"
+)
+
+// TestCompletedAtAcrossDST renders completion times on both sides of the
+// Europe/Bucharest DST changes in 2026, including the repeated hour in
+// October, to check the offset is taken from each timestamp.
+func TestCompletedAtAcrossDST(t *testing.T) {
+	application := newTestApp(t, "-tz", "Europe/Bucharest")
+
+	tests := []struct {
+		stored, want string
+	}{
+		{"2026-03-29 00:59:00", "2026-03-29 02:59"},
+		{"2026-03-29 01:00:00", "2026-03-29 04:00"},
+		{"2026-10-25 00:30:00", "2026-10-25 03:30"},
+		{"2026-10-25 01:30:00", "2026-10-25 03:30"},
+		{"2026-10-25 02:00:00", "2026-10-25 04:00"},
+	}
+	for _, test := range tests {
+		task, err := application.createTask("completed at "+test.stored, 0, defaultListID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := application.db.Exec("UPDATE tasks SET completed = 1, completed_at = ? WHERE id = ?", test.stored, task.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := application.serve(httptest.NewRequest(http.MethodGet, "/getCompletedTasks", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", recorder.Code, recorder.Body)
+	}
+	body := recorder.Body.String()
+	for _, test := range tests {
+		want := `title="Completed ` + test.want + `"`
+		if !strings.Contains(body, want) {
+			t.Errorf("stored %s: body does not contain %s", test.stored, want)
+		}
+	}
+	// The two October times share a wall clock but are an hour apart
+	if n := strings.Count(body, `title="Completed 2026-10-25 03:30"`); n != 2 {
+		t.Errorf("found 03:30 %d times, want 2", n)
+	}
+}
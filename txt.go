@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxTextBodyBytes bounds the body of POST /txt, which is one task.
+const maxTextBodyBytes = 64 << 10
+
+// writeTextTask writes a task as one line of the plain-text API, e.g.
+// "[ ] 42 Buy milk".
+func (application *App) writeTextTask(w io.Writer, task Task) {
+	box := "[ ]"
+	if task.Completed {
+		box = "[x]"
+	}
+	fmt.Fprintf(w, "%s %s %s\n", box, application.ids.encode(task.ID), task.Task)
+}
+
+// GetTextTasks lists the pending tasks of a list one per line, for use
+// from shell scripts.
+func (application *App) GetTextTasks(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.FormValue("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+
+	pending := false
+	tasks, err := application.findTasks(TaskFilter{Completed: &pending, ListID: &listID})
+	if err != nil {
+		dbError(response, "Error fetching tasks", err)
+		return
+	}
+
+	response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, task := range tasks {
+		application.writeTextTask(response, task)
+	}
+}
+
+// AddTextTask adds the raw request body as a task to the listId list and
+// answers with its line in the GET /txt format.
+func (application *App) AddTextTask(response http.ResponseWriter, request *http.Request) {
+	listID, err := parseListID(request.URL.Query().Get("listId"))
+	if err != nil {
+		http.Error(response, "Invalid list id", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(response, request.Body, maxTextBodyBytes))
+	if err != nil {
+		http.Error(response, "Error reading body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Allow the trailing newline of echo and here-documents
+	task := application.normalize(strings.TrimRight(string(body), "\r\n"))
+	if task == "" {
+		http.Error(response, "Task cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if application.containsBlockedWord(task) {
+		http.Error(response, "Task contains a blocked word", http.StatusBadRequest)
+		return
+	}
+
+	created, err := application.createTask(task, 0, listID)
+	if errors.Is(err, errListNotFound) {
+		http.Error(response, "List not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		dbError(response, "Error adding task", err)
+		return
+	}
+	response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	response.WriteHeader(http.StatusCreated)
+	application.writeTextTask(response, created)
+}
@@ -0,0 +1,34 @@
+package main
+
+import "net/http"
+
+// buildVersion identifies the build. Release builds set it with
+//
+//	go build -ldflags "-X main.buildVersion=v1.2.3"
+var buildVersion = "dev"
+
+// VersionInfo is the body of GET /admin/version.
+type VersionInfo struct {
+	Build string `json:"build"`
+	// Schema is the highest applied migration and Migrations the number
+	// this build knows, so a deployment is current when they match.
+	Schema     int    `json:"schema"`
+	Migrations int    `json:"migrations"`
+	SQLite     string `json:"sqlite"`
+}
+
+// GetVersion reports the build version, the applied schema version and the
+// version of the linked SQLite library.
+func (application *App) GetVersion(response http.ResponseWriter, request *http.Request) {
+	info := VersionInfo{Build: buildVersion, Migrations: len(migrations)}
+
+	application.mu.Lock()
+	err := application.queryRow("SELECT COALESCE(MAX(version), 0), sqlite_version() FROM schema_migrations").Scan(&info.Schema, &info.SQLite)
+	application.mu.Unlock()
+
+	if err != nil {
+		dbError(response, "Error reading schema version", err)
+		return
+	}
+	writeJSON(response, http.StatusOK, info)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// deleteTokenTTL is how long a token from /api/v1/tasks/delete-token can be
+// used to confirm deleting every task.
+const deleteTokenTTL = 5 * time.Minute
+
+// deleteToken is the confirmation token for the next delete-all request.
+// Only the most recently issued token is valid and it can be used once.
+type deleteToken struct {
+	value   string
+	expires time.Time
+}
+
+// APIGetDeleteToken issues a token that confirms a following
+// DELETE /api/v1/tasks. Issuing a new token invalidates the previous one.
+func (application *App) APIGetDeleteToken(response http.ResponseWriter, request *http.Request) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		http.Error(response, "Error generating token: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token := deleteToken{value: hex.EncodeToString(raw), expires: time.Now().Add(deleteTokenTTL)}
+
+	application.mu.Lock()
+	application.deleteToken = token
+	application.mu.Unlock()
+
+	writeJSON(response, http.StatusOK, map[string]any{"token": token.value, "expiresAt": token.expires.UTC()})
+}
+
+// APIDeleteAllTasks deletes every task in one transaction and returns how
+// many were removed. The confirm query parameter must carry an unexpired
+// token from APIGetDeleteToken.
+func (application *App) APIDeleteAllTasks(response http.ResponseWriter, request *http.Request) {
+	confirm := request.URL.Query().Get("confirm")
+
+	application.mu.Lock()
+	token := application.deleteToken
+	valid := confirm != "" && time.Now().Before(token.expires) &&
+		subtle.ConstantTimeCompare([]byte(confirm), []byte(token.value)) == 1
+	if !valid {
+		application.mu.Unlock()
+		http.Error(response, "Missing or invalid confirmation token", http.StatusBadRequest)
+		return
+	}
+	application.deleteToken = deleteToken{}
+
+	deleted, err := application.deleteAllTasks()
+	application.recordWrite(err)
+	if err == nil {
+		application.counts = taskCounts{}
+		application.streak = streakCache{}
+	}
+	application.mu.Unlock()
+
+	if err != nil {
+		dbError(response, "Error deleting tasks", err)
+		return
+	}
+	for _, task := range deleted {
+		runHooks("delete", application.hooks.OnDelete, task)
+	}
+	writeJSON(response, http.StatusOK, map[string]int{"deleted": len(deleted)})
+}
+
+// deleteAllTasks removes every task in one transaction and returns them.
+// The caller must hold the mutex.
+func (application *App) deleteAllTasks() ([]Task, error) {
+	tx, err := application.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("DELETE FROM tasks RETURNING " + taskColumns)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := scanTasks(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}